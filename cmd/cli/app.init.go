@@ -20,14 +20,31 @@ var encryptionKeyValidityPeriod int
 var signingKeyValidityPeriod int
 var applicationInitializationReason string
 
+var applicationGenerateKeys bool
+var applicationForce bool
+var applicationNonInteractive bool
+var applicationRSAPrivateKeyPath string
+var applicationRSAPublicKeyPath string
+var applicationEd25519PrivateKeyPath string
+var applicationEd25519PublicKeyPath string
+
 var initAppCmd = &cobra.Command{
 	Use:   "app",
 	Short: "Initialize app configuration",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		rootCtx := cmd.Context()
 		app := rootCtx.Value(constants.Application).(*setup.CLIApplication)
 
+		if encryptionKeyValidityPeriod <= 0 || signingKeyValidityPeriod <= 0 {
+			return exitErrorf(ExitUsage, "--encryption-validity and --signing-validity must be positive")
+		}
+
+		existingKeyPathGiven := applicationRSAPrivateKeyPath != "" || applicationRSAPublicKeyPath != "" || applicationEd25519PrivateKeyPath != "" || applicationEd25519PublicKeyPath != ""
+		if applicationGenerateKeys && existingKeyPathGiven {
+			return exitErrorf(ExitUsage, "--generate cannot be combined with existing key paths")
+		}
+
 		// set ulid in context
 		ulid := util.GenerateULID().String()
 
@@ -36,87 +53,84 @@ var initAppCmd = &cobra.Command{
 		// check if this applicationSlug is already registered in registered applications of cli config
 		isApplicationRegistered := slices.Contains(app.Config.GetRegisteredApplications(), applicationSlug)
 
-		if isApplicationRegistered {
-			fmt.Printf("\nThis application: %s is already registered", applicationSlug)
-
-			fmt.Println("\n\nDo you want to re-initialize the application? \n[1:Yes || 2:No]")
-			fmt.Print("\nEnter your choice: ")
+		if isApplicationRegistered && !applicationForce {
+			if applicationNonInteractive {
+				return exitErrorf(ExitAborted, "application %s is already registered, use --force to re-initialize", applicationSlug)
+			}
 
-			var choice int
-			fmt.Scanln(&choice)
+			fmt.Printf("\nThis application: %s is already registered", applicationSlug)
 
+			choice, err := promptChoice(applicationNonInteractive, "Do you want to re-initialize the application?", "Yes", "No")
+			if err != nil {
+				return err
+			}
 			if choice == 2 {
-				return
-			} else if choice != 1 {
-				fmt.Println("Invalid choice")
-				return
+				return nil
 			}
 		}
 
 		fmt.Println("\nInitializing application...")
 
-		fmt.Println("\nChoose an option:")
-		fmt.Println("1) Generate a new key pair")
-		fmt.Println("2) Use your own existing key pair")
-		fmt.Print("Enter your choice: ")
-
-		var choice int
-
-		var rsaPublicKeyPath string
-		var rsaPrivateKeyPath string
-		var ed25519PublicKeyPath string
-		var ed25519PrivateKeyPath string
+		choice := 0
+		switch {
+		case applicationGenerateKeys:
+			choice = 1
+		case existingKeyPathGiven:
+			choice = 2
+		default:
+			var err error
+			choice, err = promptChoice(applicationNonInteractive, "Choose an option:", "Generate a new key pair", "Use your own existing key pair")
+			if err != nil {
+				return err
+			}
+		}
 
-		fmt.Scanln(&choice)
+		keyLoader := keymanagementfs.NewFSKeyLoader()
+		keySaver := keymanagementfs.NewFSKeySaver()
+		keyConverter := keymanagementfs.NewFSKeyConverter()
+		keyService := service.NewKeyService(keyLoader, keyConverter, keySaver, nil, app.Logger, app.Config)
+		keyHandler := keyhandler.NewKeyHandler(keyService)
 
 		switch choice {
 		case 1:
 			fmt.Println("Generating new key pair...")
 
-			encryptionKeyValidityPeriod = constants.EncryptionKeyValidityPeriod
-			signingKeyValidityPeriod = constants.SigningKeyValidityPeriod
-
-			keyLoader := keymanagementfs.NewFSKeyLoader()
-			keySaver := keymanagementfs.NewFSKeySaver()
-			keyConverter := keymanagementfs.NewFSKeyConverter()
-			keyService := service.NewKeyService(keyLoader, keyConverter, keySaver, nil, app.Logger, app.Config)
-			keyHandler := keyhandler.NewKeyHandler(keyService)
 			if err := keyHandler.HandleApplicationGenerateKeyPair(applicationSlug, ulid); err != nil {
 				app.Logger.Error("Error while generating key pair", zap.String("error", err.Error()))
-				return
+				return &ExitError{Code: ExitKeyError, Err: err}
 			}
 			fmt.Println("Key pair generated and saved successfully")
 		case 2:
-			fmt.Println("Please provide the path to your application's public and private key files.")
-
-			fmt.Print("RSA Public key path: ")
-			fmt.Scanln(&rsaPublicKeyPath)
+			if !existingKeyPathGiven && !applicationNonInteractive {
+				fmt.Println("Please provide the path to your application's public and private key files.")
+			}
 
-			fmt.Print("RSA Private key path: ")
-			fmt.Scanln(&rsaPrivateKeyPath)
+			rsaPublicKeyPath, err := promptPath(applicationNonInteractive, "RSA Public key path", "rsa-public-key", applicationRSAPublicKeyPath)
+			if err != nil {
+				return err
+			}
 
-			fmt.Print("Ed25519 Public key path: ")
-			fmt.Scanln(&ed25519PublicKeyPath)
+			rsaPrivateKeyPath, err := promptPath(applicationNonInteractive, "RSA Private key path", "rsa-private-key", applicationRSAPrivateKeyPath)
+			if err != nil {
+				return err
+			}
 
-			fmt.Print("Ed25519 Private key path: ")
-			fmt.Scanln(&ed25519PrivateKeyPath)
+			ed25519PublicKeyPath, err := promptPath(applicationNonInteractive, "Ed25519 Public key path", "ed25519-public-key", applicationEd25519PublicKeyPath)
+			if err != nil {
+				return err
+			}
 
-			encryptionKeyValidityPeriod = constants.EncryptionKeyValidityPeriod
-			signingKeyValidityPeriod = constants.SigningKeyValidityPeriod
+			ed25519PrivateKeyPath, err := promptPath(applicationNonInteractive, "Ed25519 Private key path", "ed25519-private-key", applicationEd25519PrivateKeyPath)
+			if err != nil {
+				return err
+			}
 
-			keyLoader := keymanagementfs.NewFSKeyLoader()
-			keySaver := keymanagementfs.NewFSKeySaver()
-			keyConverter := keymanagementfs.NewFSKeyConverter()
-			keyService := service.NewKeyService(keyLoader, keyConverter, keySaver, nil, app.Logger, app.Config)
-			keyHandler := keyhandler.NewKeyHandler(keyService)
 			if err := keyHandler.HandleApplicationExistingKeyPair(applicationSlug, ulid, rsaPrivateKeyPath, rsaPublicKeyPath, ed25519PrivateKeyPath, ed25519PublicKeyPath); err != nil {
 				app.Logger.Error("Error while handling existing key pair", zap.String("error", err.Error()))
-				return
+				return &ExitError{Code: ExitKeyError, Err: err}
 			}
 
 			fmt.Println("Key pair loaded successfully")
-		default:
-			app.Logger.Info("Invalid choice")
 		}
 
 		if !isApplicationRegistered {
@@ -124,22 +138,34 @@ var initAppCmd = &cobra.Command{
 		}
 
 		app.Config.AddApplicationSlug(applicationSlug)
-		app.Config.AddApplicationKeysPaths(constants.RapidBridgeData+"/application/"+applicationSlug+"/"+ulid+"/rsa_private_key.pem", constants.RapidBridgeData+"/application/"+applicationSlug+"/"+ulid+"/rsa_public_key.pem", constants.RapidBridgeData+"/application/"+applicationSlug+"/"+ulid+"/ed25519_private_key.pem", constants.RapidBridgeData+"/application/"+applicationSlug+"/"+ulid+"/ed25519_public_key.pem")
+		dataDir := app.Config.GetDataDir()
+		app.Config.AddApplicationKeysPaths(util.GetRSAPrivateKeyPath(dataDir, applicationSlug, ulid), util.GetRSAPublicKeyPath(dataDir, applicationSlug, ulid), util.GetEd25519PrivateKeyPath(dataDir, applicationSlug, ulid), util.GetEd25519PublicKeyPath(dataDir, applicationSlug, ulid))
 		app.Config.AddKeysValidityPeriod(encryptionKeyValidityPeriod, signingKeyValidityPeriod)
 
 		app.Config.AddApplicationUlid(ulid)
 
 		if err := app.Config.SaveApplicationConfigToFile(); err != nil {
 			app.Logger.Error("Error while saving config", zap.String("error", err.Error()))
-			return
+			return &ExitError{Code: ExitConfigError, Err: err}
 		}
 
-		app.Logger.Info("Application configuration saved successfully")
+		app.Logger.Info("Application configuration saved successfully", zap.String("key_version", ulid))
 
+		return nil
 	},
 }
 
 func init() {
 	initAppCmd.Flags().StringVar(&applicationSlug, "slug", "", "App slug identifier (required)")
 	initAppCmd.MarkFlagRequired("slug")
+
+	initAppCmd.Flags().BoolVar(&applicationGenerateKeys, "generate", false, "Generate a new RSA and Ed25519 key pair")
+	initAppCmd.Flags().StringVar(&applicationRSAPrivateKeyPath, "rsa-private-key", "", "Path to an existing RSA private key")
+	initAppCmd.Flags().StringVar(&applicationRSAPublicKeyPath, "rsa-public-key", "", "Path to an existing RSA public key")
+	initAppCmd.Flags().StringVar(&applicationEd25519PrivateKeyPath, "ed25519-private-key", "", "Path to an existing Ed25519 private key")
+	initAppCmd.Flags().StringVar(&applicationEd25519PublicKeyPath, "ed25519-public-key", "", "Path to an existing Ed25519 public key")
+	initAppCmd.Flags().BoolVar(&applicationForce, "force", false, "Re-initialize the application if it is already registered")
+	initAppCmd.Flags().BoolVar(&applicationNonInteractive, "non-interactive", false, "Fail instead of prompting for missing input")
+	initAppCmd.Flags().IntVar(&encryptionKeyValidityPeriod, "encryption-validity", constants.EncryptionKeyValidityPeriod, "Validity of the RSA encryption keys in days")
+	initAppCmd.Flags().IntVar(&signingKeyValidityPeriod, "signing-validity", constants.SigningKeyValidityPeriod, "Validity of the Ed25519 signing keys in days")
 }
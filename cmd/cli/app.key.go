@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/adapter/credentials"
+	"rapid-bridge/internal/service"
+	"rapid-bridge/internal/setup"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var apiKeyName string
+var apiKeyValidDays int
+
+var appKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the API keys applications authenticate to the bridge with",
+}
+
+var appKeyCreateCmd = &cobra.Command{
+	Use:   "create <slug>",
+	Short: "Issue an API key for an application",
+	Long: `Issue an API key for an application and print it.
+
+The key is shown only once; the bridge stores a hash of it. Callers send it as
+"Authorization: Bearer <key>" together with X-Source-Slug set to the same
+application. A running server accepts it immediately.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := newRegistryService(cmd).ShowApplication(args[0]); err != nil {
+			return err
+		}
+
+		issued, err := newCredentialService(cmd).IssueAPIKey(args[0], apiKeyName, time.Duration(apiKeyValidDays)*24*time.Hour)
+		if err != nil {
+			return &ExitError{Code: ExitConfigError, Err: err}
+		}
+
+		if manageOutputJSON {
+			return printJSON(issued)
+		}
+
+		fmt.Printf("API key %s issued for %s, valid until %s\n\n", issued.ID, args[0], formatExpiry(issued.APIKey))
+		fmt.Println(issued.Key)
+		fmt.Println("\nStore it now, it cannot be shown again.")
+		return nil
+	},
+}
+
+var appKeyListCmd = &cobra.Command{
+	Use:   "list <slug>",
+	Short: "List an application's API keys",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := newRegistryService(cmd).ShowApplication(args[0]); err != nil {
+			return err
+		}
+
+		apiKeys, err := newCredentialService(cmd).ListAPIKeys(args[0])
+		if err != nil {
+			return &ExitError{Code: ExitConfigError, Err: err}
+		}
+
+		if manageOutputJSON {
+			return printJSON(apiKeys)
+		}
+
+		rows := [][]string{}
+		for _, apiKey := range apiKeys {
+			rows = append(rows, []string{
+				apiKey.ID,
+				valueOrDash(apiKey.Name),
+				apiKeyStatus(apiKey),
+				formatDate(apiKey.CreatedAt),
+				formatExpiry(apiKey),
+			})
+		}
+		printTable([]string{"ID", "NAME", "STATUS", "CREATED", "EXPIRES"}, rows)
+
+		return nil
+	},
+}
+
+var appKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <slug> <key-id>",
+	Short: "Revoke an API key; a running server rejects it immediately",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := newRegistryService(cmd).ShowApplication(args[0]); err != nil {
+			return err
+		}
+
+		if err := newCredentialService(cmd).RevokeAPIKey(args[0], args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("API key %s of %s revoked\n", args[1], args[0])
+		return nil
+	},
+}
+
+func apiKeyStatus(apiKey port.APIKey) string {
+	switch {
+	case apiKey.RevokedAt != nil:
+		return "revoked"
+	case apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+func formatExpiry(apiKey port.APIKey) string {
+	if apiKey.ExpiresAt == nil {
+		return "never"
+	}
+	return formatDate(*apiKey.ExpiresAt)
+}
+
+func newCredentialService(cmd *cobra.Command) *service.CredentialService {
+	app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+
+	return service.NewCredentialService(credentials.NewFSCredentialStore(app.Config.GetDataDir()), app.Logger)
+}
+
+func init() {
+	appKeyCreateCmd.Flags().StringVar(&apiKeyName, "name", "", "Label to tell keys apart, e.g. the calling service")
+	appKeyCreateCmd.Flags().IntVar(&apiKeyValidDays, "valid-days", 0, "Days until the key expires, 0 for never")
+	appKeyCreateCmd.Flags().BoolVar(&manageOutputJSON, "json", false, "Print JSON instead of text")
+	appKeyListCmd.Flags().BoolVar(&manageOutputJSON, "json", false, "Print JSON instead of a table")
+
+	appKeyCmd.AddCommand(appKeyCreateCmd)
+	appKeyCmd.AddCommand(appKeyListCmd)
+	appKeyCmd.AddCommand(appKeyRevokeCmd)
+
+	appCmd.AddCommand(appKeyCmd)
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"fmt"
+	"rapid-bridge/constants"
+	httpclient "rapid-bridge/internal/adapter/http_client"
+	keymanagementfs "rapid-bridge/internal/adapter/keymanagement_fs"
+	"rapid-bridge/internal/service"
+	"rapid-bridge/internal/setup"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var manageOutputJSON bool
+var manageAssumeYes bool
+var manageKeepKeys bool
+
+var appCmd = &cobra.Command{
+	Use:   "app",
+	Short: "Manage registered applications",
+}
+
+var appListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered applications",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryService := newRegistryService(cmd)
+
+		applications := registryService.ListApplications()
+		if manageOutputJSON {
+			return printJSON(applications)
+		}
+
+		rows := [][]string{}
+		for _, application := range applications {
+			rows = append(rows, []string{
+				application.Slug,
+				applicationStatus(application),
+				valueOrDash(application.KeyVersion),
+				formatDate(application.RSAKeysValidUntil),
+				formatDate(application.Ed25519KeysValidUntil),
+			})
+		}
+		printTable([]string{"SLUG", "STATUS", "KEY VERSION", "RSA VALID UNTIL", "ED25519 VALID UNTIL"}, rows)
+
+		return nil
+	},
+}
+
+var appShowCmd = &cobra.Command{
+	Use:   "show <slug>",
+	Short: "Show an application's configuration and key versions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryService := newRegistryService(cmd)
+
+		application, err := registryService.ShowApplication(args[0])
+		if err != nil {
+			return err
+		}
+
+		if manageOutputJSON {
+			return printJSON(application)
+		}
+
+		fmt.Printf("Slug:                  %s\n", application.Slug)
+		fmt.Printf("Status:                %s\n", applicationStatus(application))
+		fmt.Printf("Key version:           %s\n", valueOrDash(application.KeyVersion))
+		fmt.Printf("RSA valid until:       %s\n", formatDate(application.RSAKeysValidUntil))
+		fmt.Printf("Ed25519 valid until:   %s\n", formatDate(application.Ed25519KeysValidUntil))
+		fmt.Printf("RSA public key:        %s\n", valueOrDash(application.RSAPublicKeyPath))
+		fmt.Printf("Ed25519 public key:    %s\n", valueOrDash(application.Ed25519PublicKeyPath))
+		fmt.Printf("Key versions on disk:  %s\n", valueOrDash(strings.Join(application.KeyVersions, ", ")))
+		if application.ConfigError != "" {
+			fmt.Printf("Config error:          %s\n", application.ConfigError)
+		}
+
+		return nil
+	},
+}
+
+var appDisableCmd = &cobra.Command{
+	Use:   "disable <slug>",
+	Short: "Disable an application; a running server rejects its requests immediately",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := newRegistryService(cmd).DisableApplication(args[0]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Application %s disabled\n", args[0])
+		return nil
+	},
+}
+
+var appEnableCmd = &cobra.Command{
+	Use:   "enable <slug>",
+	Short: "Enable a previously disabled application",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := newRegistryService(cmd).EnableApplication(args[0]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Application %s enabled\n", args[0])
+		return nil
+	},
+}
+
+var appRemoveCmd = &cobra.Command{
+	Use:   "remove <slug>",
+	Short: "Unregister an application and delete its key directory",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryService := newRegistryService(cmd)
+
+		if _, err := registryService.ShowApplication(args[0]); err != nil {
+			return err
+		}
+
+		question := fmt.Sprintf("Remove application %s and delete all of its keys?", args[0])
+		if manageKeepKeys {
+			question = fmt.Sprintf("Unregister application %s, keeping its keys on disk?", args[0])
+		}
+		if err := confirm(manageAssumeYes, question); err != nil {
+			return err
+		}
+
+		if err := registryService.RemoveApplication(args[0], manageKeepKeys); err != nil {
+			return err
+		}
+
+		fmt.Printf("Application %s removed\n", args[0])
+		return nil
+	},
+}
+
+func applicationStatus(application service.ApplicationInfo) string {
+	if application.Disabled {
+		return "disabled"
+	}
+	return "enabled"
+}
+
+func newRegistryService(cmd *cobra.Command) *service.RegistryService {
+	app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+
+	keyLoader := keymanagementfs.NewFSKeyLoader()
+	keyService := service.NewKeyService(keyLoader, keymanagementfs.NewFSKeyConverter(), keymanagementfs.NewFSKeySaver(), httpclient.NewHttpClient(app.Logger), app.Logger, app.Config)
+
+	return service.NewRegistryService(app.Config, keyLoader, keyService, app.Logger)
+}
+
+func init() {
+	appListCmd.Flags().BoolVar(&manageOutputJSON, "json", false, "Print JSON instead of a table")
+	appShowCmd.Flags().BoolVar(&manageOutputJSON, "json", false, "Print JSON instead of text")
+	appRemoveCmd.Flags().BoolVar(&manageAssumeYes, "yes", false, "Do not ask for confirmation")
+	appRemoveCmd.Flags().BoolVar(&manageKeepKeys, "keep-keys", false, "Only unregister, leave the key directory on disk")
+
+	appCmd.AddCommand(appListCmd)
+	appCmd.AddCommand(appShowCmd)
+	appCmd.AddCommand(appDisableCmd)
+	appCmd.AddCommand(appEnableCmd)
+	appCmd.AddCommand(appRemoveCmd)
+
+	RootCmd.AddCommand(appCmd)
+}
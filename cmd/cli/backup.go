@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/adapter/logger"
+	"rapid-bridge/internal/service"
+	"rapid-bridge/pkg/config"
+	"rapid-bridge/pkg/security/age"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var backupOut string
+var backupPassphraseFile string
+var backupForce bool
+var restoreForce bool
+var restoreDryRun bool
+var backupOutputJSON bool
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write the data directory to an encrypted backup bundle",
+	Long: `Write core.json, every application and bank config and all key versions to a
+single encrypted archive.
+
+The archive is a tar file encrypted with a passphrase in the age format, so it
+can also be opened with "age -d". The passphrase is read from --passphrase-file
+or from $` + constants.BackupPassphraseEnv + `.`,
+	Example: `  ` + constants.BackupPassphraseEnv + `=... rapid-bridge backup --out bundle.tar.age`,
+	Args:    cobra.NoArgs,
+	// a registry that fails to load is exactly what should be backed up
+	// before trying to repair it
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if backupOut != "-" && !backupForce {
+			if _, err := os.Stat(backupOut); err == nil {
+				return exitErrorf(ExitAborted, "%s already exists, pass --force to overwrite it", backupOut)
+			}
+		}
+
+		passphrase, err := readBackupPassphrase()
+		if err != nil {
+			return err
+		}
+
+		backupService, err := newBackupService()
+		if err != nil {
+			return err
+		}
+
+		bundle, manifest, err := backupService.Create(passphrase)
+		if err != nil {
+			return &ExitError{Code: ExitConfigError, Err: err}
+		}
+
+		if backupOut == "-" {
+			_, err = os.Stdout.Write(bundle)
+			return err
+		}
+		if err := writeFileAtomic(backupOut, bundle); err != nil {
+			return err
+		}
+
+		if backupOutputJSON {
+			return printJSON(manifest)
+		}
+		fmt.Printf("Backed up %d applications, %d banks (%d files) to %s\n", len(manifest.Applications), len(manifest.Banks), len(manifest.Files), backupOut)
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <bundle>",
+	Short: "Restore the data directory from a backup bundle",
+	Long: `Restore the data directory from a bundle written by "rapid-bridge backup".
+
+Every file is checked against the bundle's manifest and every key is validated
+before the data directory is touched. An existing registry is only replaced
+with --force, and is then kept next to it as <data-dir>.pre-restore-<time>.
+Use - to read the bundle from stdin.`,
+	Example: `  rapid-bridge restore bundle.tar.age --dry-run
+  rapid-bridge restore bundle.tar.age --force`,
+	Args:             cobra.ExactArgs(1),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var bundle []byte
+		var err error
+		if args[0] == "-" {
+			bundle, err = io.ReadAll(os.Stdin)
+		} else {
+			bundle, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return exitErrorf(ExitUsage, "failed to read the bundle: %v", err)
+		}
+
+		passphrase, err := readBackupPassphrase()
+		if err != nil {
+			return err
+		}
+
+		backupService, err := newBackupService()
+		if err != nil {
+			return err
+		}
+
+		result, err := backupService.Restore(bundle, passphrase, restoreForce, restoreDryRun)
+		if err != nil {
+			return err
+		}
+
+		if backupOutputJSON {
+			return printJSON(result)
+		}
+
+		manifest := result.Manifest
+		fmt.Printf("Backup from %s, schema version %d: %d applications, %d banks, %d files\n", manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), manifest.SchemaVersion, len(manifest.Applications), len(manifest.Banks), len(manifest.Files))
+		if result.DryRun {
+			fmt.Println("Integrity and key checks passed, nothing was restored (--dry-run)")
+			return nil
+		}
+		if result.PreviousDataDir != "" {
+			fmt.Printf("Previous data directory moved to %s\n", result.PreviousDataDir)
+		}
+		fmt.Printf("Restored into %s\n", result.DataDir)
+		if manifest.SchemaVersion < constants.DataSchemaVersion {
+			fmt.Println("The backup predates the current schema version, run rapid-bridge migrate")
+		}
+		return nil
+	},
+}
+
+func newBackupService() (*service.BackupService, error) {
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		return nil, err
+	}
+	return service.NewBackupService(config.ResolveDataDir(dataDir, configFile), age.DefaultWorkFactor, zapLogger), nil
+}
+
+// readBackupPassphrase reads the passphrase from --passphrase-file, or from
+// the environment. There is no prompt: it would echo the passphrase.
+func readBackupPassphrase() (string, error) {
+	if backupPassphraseFile != "" {
+		data, err := os.ReadFile(backupPassphraseFile)
+		if err != nil {
+			return "", exitErrorf(ExitUsage, "failed to read --passphrase-file: %v", err)
+		}
+		passphrase := strings.TrimRight(string(data), "\r\n")
+		if passphrase == "" {
+			return "", exitErrorf(ExitUsage, "%s is empty", backupPassphraseFile)
+		}
+		return passphrase, nil
+	}
+
+	if passphrase := os.Getenv(constants.BackupPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	return "", exitErrorf(ExitInputRequired, "a passphrase is required: pass --passphrase-file or set %s", constants.BackupPassphraseEnv)
+}
+
+// writeFileAtomic writes data readable by the owner only, through a
+// temporary file so that an interrupted backup never leaves a truncated
+// bundle under the final name.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func init() {
+	for _, command := range []*cobra.Command{backupCmd, restoreCmd} {
+		command.Flags().StringVar(&backupPassphraseFile, "passphrase-file", "", "File holding the passphrase (defaults to $"+constants.BackupPassphraseEnv+")")
+		command.Flags().BoolVar(&backupOutputJSON, "json", false, "Print the manifest as JSON")
+	}
+
+	backupCmd.Flags().StringVar(&backupOut, "out", "", "Bundle to write, or - for stdout (required)")
+	backupCmd.Flags().BoolVar(&backupForce, "force", false, "Overwrite an existing bundle")
+	backupCmd.MarkFlagRequired("out")
+
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace an existing registry")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Only check the bundle's integrity and keys")
+
+	RootCmd.AddCommand(backupCmd)
+	RootCmd.AddCommand(restoreCmd)
+}
@@ -2,13 +2,18 @@ package cli
 
 import (
 	"fmt"
+	"path/filepath"
 	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
 	httpclient "rapid-bridge/internal/adapter/http_client"
 	keymanagementfs "rapid-bridge/internal/adapter/keymanagement_fs"
 	"rapid-bridge/internal/handler"
 	"rapid-bridge/internal/service"
+	"rapid-bridge/pkg/security/tlsconfig"
+	"rapid-bridge/pkg/util"
 
 	"rapid-bridge/internal/setup"
+	"reflect"
 	"slices"
 
 	"github.com/spf13/cobra"
@@ -17,81 +22,108 @@ import (
 
 var bankSlug string
 
-var rapidUrl string
+var rapidUrls []string
+
+var bankFetchKeys bool
+var bankForce bool
+var bankNonInteractive bool
+var bankRSAPublicKeyPath string
+var bankEd25519PublicKeyPath string
+var bankTLSCA string
+var bankTLSCert string
+var bankTLSKey string
+var bankTLSServerName string
+var bankTLSMinVersion string
+var bankTLSPins []string
+var bankForwardHeaders []string
 
 var initBankCmd = &cobra.Command{
 	Use:   "bank",
 	Short: "Initialize bank configuration",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
 
+		existingKeyPathGiven := bankRSAPublicKeyPath != "" || bankEd25519PublicKeyPath != ""
+		if bankFetchKeys && existingKeyPathGiven {
+			return exitErrorf(ExitUsage, "--fetch cannot be combined with existing key paths")
+		}
+
+		upstreamTLS, err := bankUpstreamTLS()
+		if err != nil {
+			return err
+		}
+
 		// check if this bankSlug is already registered in registered banks of cli config
 		isBankRegistered := slices.Contains(app.Config.GetRegisteredBanks(), bankSlug)
 
-		if isBankRegistered {
-			fmt.Printf("\nThis bank: %s is already registered", bankSlug)
-			fmt.Println("\n\nDo you want to re-initialize the bank? \n[1:Yes || 2:No]")
-			fmt.Print("\nEnter your choice: ")
+		if isBankRegistered && !bankForce {
+			if bankNonInteractive {
+				return exitErrorf(ExitAborted, "bank %s is already registered, use --force to re-initialize", bankSlug)
+			}
 
-			var choice int
-			fmt.Scanln(&choice)
+			fmt.Printf("\nThis bank: %s is already registered", bankSlug)
 
+			choice, err := promptChoice(bankNonInteractive, "Do you want to re-initialize the bank?", "Yes", "No")
+			if err != nil {
+				return err
+			}
 			if choice == 2 {
-				return
-			} else if choice != 1 {
-				fmt.Println("Invalid choice")
-				return
+				return nil
 			}
 		}
 
 		fmt.Println("\nInitializing Bank...")
 
-		fmt.Println("Choose an option:")
-		fmt.Println("1) Fetch Bank Public Keys")
-		fmt.Println("2) You already have the Bank Public Keys")
-		fmt.Print("Enter your choice: ")
-
-		var choice int
-
-		var rsaPublicKeyPath string
-		var ed25519PublicKeyPath string
+		choice := 0
+		switch {
+		case bankFetchKeys:
+			choice = 1
+		case existingKeyPathGiven:
+			choice = 2
+		default:
+			var err error
+			choice, err = promptChoice(bankNonInteractive, "Choose an option:", "Fetch Bank Public Keys", "You already have the Bank Public Keys")
+			if err != nil {
+				return err
+			}
+		}
 
-		fmt.Scanln(&choice)
+		http_client := httpclient.NewHttpClient(app.Logger)
+		keyService := service.NewKeyService(keymanagementfs.NewFSKeyLoader(), keymanagementfs.NewFSKeyConverter(), keymanagementfs.NewFSKeySaver(), http_client, app.Logger, app.Config)
+		keyHandler := handler.NewKeyHandler(keyService)
 
 		switch choice {
 		case 1:
 			fmt.Println("Fetching Bank Public Keys...")
 
-			http_client := httpclient.NewHttpClient(app.Logger)
-			keyService := service.NewKeyService(keymanagementfs.NewFSKeyLoader(), keymanagementfs.NewFSKeyConverter(), keymanagementfs.NewFSKeySaver(), http_client, app.Logger, app.Config)
-			keyHandler := handler.NewKeyHandler(keyService)
-			if err := keyHandler.HandleBankFetchKeys(rapidUrl, bankSlug); err != nil {
+			if err := keyHandler.HandleBankFetchKeys(rapidUrls, bankSlug, upstreamTLS); err != nil {
 				app.Logger.Error("Error while fetching bank public keys", zap.String("error", err.Error()))
-				return
+				return &ExitError{Code: ExitUpstreamError, Err: err}
 			}
 
 			fmt.Println("Bank Public Keys fetched successfully")
 		case 2:
-			fmt.Println("Please provide the path to your bank's public and private key files.")
+			if !existingKeyPathGiven && !bankNonInteractive {
+				fmt.Println("Please provide the path to your bank's public key files.")
+			}
 
-			fmt.Print("RSA Public key path: ")
-			fmt.Scanln(&rsaPublicKeyPath)
+			rsaPublicKeyPath, err := promptPath(bankNonInteractive, "RSA Public key path", "rsa-public-key", bankRSAPublicKeyPath)
+			if err != nil {
+				return err
+			}
 
-			fmt.Print("Ed25519 Public key path: ")
-			fmt.Scanln(&ed25519PublicKeyPath)
+			ed25519PublicKeyPath, err := promptPath(bankNonInteractive, "Ed25519 Public key path", "ed25519-public-key", bankEd25519PublicKeyPath)
+			if err != nil {
+				return err
+			}
 
-			http_client := httpclient.NewHttpClient(app.Logger)
-			keyService := service.NewKeyService(keymanagementfs.NewFSKeyLoader(), keymanagementfs.NewFSKeyConverter(), keymanagementfs.NewFSKeySaver(), http_client, app.Logger, app.Config)
-			keyHandler := handler.NewKeyHandler(keyService)
 			if err := keyHandler.HandleBankExistingKeys(bankSlug, rsaPublicKeyPath, ed25519PublicKeyPath); err != nil {
 				app.Logger.Error("Error while handling existing rsa and ed25519 keys of bank", zap.String("error", err.Error()))
-				return
+				return &ExitError{Code: ExitKeyError, Err: err}
 			}
 
 			fmt.Println("Bank RSA and ED25519 keys loaded successfully")
-		default:
-			app.Logger.Info("Invalid choice")
 		}
 
 		if !isBankRegistered {
@@ -99,24 +131,79 @@ var initBankCmd = &cobra.Command{
 		}
 
 		app.Config.AddBankSlug(bankSlug)
+		app.Config.AddBankRapidUrls(rapidUrls)
+		app.Config.AddBankTLS(upstreamTLS)
+		app.Config.AddBankForwardHeaders(bankForwardHeaders)
 
-		app.Config.AddBankKeysPaths(constants.RapidBridgeData+"/bank/"+bankSlug+"/rsa_public_key.pem", constants.RapidBridgeData+"/bank/"+bankSlug+"/ed25519_public_key.pem")
-
-		// TODO: Create a util function to create a file path without manually appending names to a string
+		app.Config.AddBankKeysPaths(util.GetBankRSAPublicKeyPath(app.Config.GetDataDir(), bankSlug), util.GetBankEd25519PublicKeyPath(app.Config.GetDataDir(), bankSlug))
 
 		if err := app.Config.SaveBankConfigToFile(); err != nil {
 			app.Logger.Error("Error while saving config to file", zap.String("error", err.Error()))
-			return
+			return &ExitError{Code: ExitConfigError, Err: err}
 		}
 
 		app.Logger.Info("Bank configuration initialized successfully")
+
+		return nil
 	},
 }
 
+// bankUpstreamTLS builds the bank's upstream TLS settings from the flags, nil
+// when none are given. Paths are stored absolute since the server may run
+// from another directory.
+func bankUpstreamTLS() (*port.UpstreamTLS, error) {
+	upstreamTLS := port.UpstreamTLS{
+		ServerName: bankTLSServerName,
+		MinVersion: bankTLSMinVersion,
+		SPKIPins:   bankTLSPins,
+	}
+
+	for _, file := range []struct {
+		flag  string
+		value string
+		field *string
+	}{
+		{"--tls-ca", bankTLSCA, &upstreamTLS.CAFile},
+		{"--tls-cert", bankTLSCert, &upstreamTLS.CertFile},
+		{"--tls-key", bankTLSKey, &upstreamTLS.KeyFile},
+	} {
+		if file.value == "" {
+			continue
+		}
+		path, err := filepath.Abs(file.value)
+		if err != nil {
+			return nil, exitErrorf(ExitUsage, "invalid %s: %v", file.flag, err)
+		}
+		*file.field = path
+	}
+
+	if reflect.DeepEqual(upstreamTLS, port.UpstreamTLS{}) {
+		return nil, nil
+	}
+
+	if _, err := tlsconfig.NewClientConfig(upstreamTLS); err != nil {
+		return nil, exitErrorf(ExitUsage, "invalid TLS settings: %v", err)
+	}
+	return &upstreamTLS, nil
+}
+
 func init() {
 	initBankCmd.Flags().StringVar(&bankSlug, "slug", "", "Bank slug identifier (required)")
 	initBankCmd.MarkFlagRequired("slug")
 
-	initBankCmd.Flags().StringVar(&rapidUrl, "rapidUrl", "", "Rapid URL (required)")
+	initBankCmd.Flags().StringSliceVar(&rapidUrls, "rapidUrl", nil, "Rapid URL, repeat or comma separate for failover endpoints (required)")
 	initBankCmd.MarkFlagRequired("rapidUrl")
+
+	initBankCmd.Flags().BoolVar(&bankFetchKeys, "fetch", false, "Fetch the bank public keys from the Rapid URL")
+	initBankCmd.Flags().StringVar(&bankRSAPublicKeyPath, "rsa-public-key", "", "Path to the bank's RSA public key")
+	initBankCmd.Flags().StringVar(&bankEd25519PublicKeyPath, "ed25519-public-key", "", "Path to the bank's Ed25519 public key")
+	initBankCmd.Flags().StringVar(&bankTLSCA, "tls-ca", "", "PEM bundle of CAs to trust for the Rapid URLs instead of the system roots")
+	initBankCmd.Flags().StringVar(&bankTLSCert, "tls-cert", "", "PEM client certificate to present to the bank")
+	initBankCmd.Flags().StringVar(&bankTLSKey, "tls-key", "", "PEM private key of --tls-cert")
+	initBankCmd.Flags().StringVar(&bankTLSServerName, "tls-server-name", "", "Server name to verify instead of the Rapid URL host")
+	initBankCmd.Flags().StringVar(&bankTLSMinVersion, "tls-min-version", "", "Lowest TLS version to accept: 1.2 (default) or 1.3")
+	initBankCmd.Flags().StringSliceVar(&bankTLSPins, "tls-pin", nil, "sha256/<base64> SPKI pin the bank's chain must contain, repeatable")
+	initBankCmd.Flags().StringSliceVar(&bankForwardHeaders, "forward-header", nil, "Application header to forward to this bank besides the global forward_headers, repeatable")
+	initBankCmd.Flags().BoolVar(&bankForce, "force", false, "Re-initialize the bank if it is already registered")
+	initBankCmd.Flags().BoolVar(&bankNonInteractive, "non-interactive", false, "Fail instead of prompting for missing input")
 }
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var bankRefreshRapidUrls []string
+
+var bankCmd = &cobra.Command{
+	Use:   "bank",
+	Short: "Manage registered banks",
+}
+
+var bankListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered banks",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryService := newRegistryService(cmd)
+
+		banks := registryService.ListBanks()
+		if manageOutputJSON {
+			return printJSON(banks)
+		}
+
+		rows := [][]string{}
+		for _, bank := range banks {
+			rows = append(rows, []string{
+				bank.Slug,
+				valueOrDash(strings.Join(bank.RapidUrls, ", ")),
+				valueOrDash(bank.ConfigError),
+			})
+		}
+		printTable([]string{"SLUG", "RAPID URLS", "ERROR"}, rows)
+
+		return nil
+	},
+}
+
+var bankShowCmd = &cobra.Command{
+	Use:   "show <slug>",
+	Short: "Show a bank's configuration and key fingerprints",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryService := newRegistryService(cmd)
+
+		bank, err := registryService.ShowBank(args[0])
+		if err != nil {
+			return err
+		}
+
+		if manageOutputJSON {
+			return printJSON(bank)
+		}
+
+		fmt.Printf("Slug:                 %s\n", bank.Slug)
+		fmt.Printf("Rapid URLs:           %s\n", valueOrDash(strings.Join(bank.RapidUrls, ", ")))
+		fmt.Printf("RSA public key:       %s\n", valueOrDash(bank.RSAPublicKeyPath))
+		fmt.Printf("RSA SHA-256:          %s\n", valueOrDash(bank.RSAPublicKeyFingerprint))
+		fmt.Printf("Ed25519 public key:   %s\n", valueOrDash(bank.Ed25519PublicKeyPath))
+		fmt.Printf("Ed25519 SHA-256:      %s\n", valueOrDash(bank.Ed25519PublicKeyFingerprint))
+		fmt.Printf("Forward headers:      %s\n", valueOrDash(strings.Join(bank.ForwardHeaders, ", ")))
+		if bank.TLS != nil {
+			fmt.Printf("TLS CA bundle:        %s\n", valueOrDash(bank.TLS.CAFile))
+			fmt.Printf("TLS client cert:      %s\n", valueOrDash(bank.TLS.CertFile))
+			fmt.Printf("TLS server name:      %s\n", valueOrDash(bank.TLS.ServerName))
+			fmt.Printf("TLS min version:      %s\n", valueOrDash(bank.TLS.MinVersion))
+			fmt.Printf("TLS SPKI pins:        %s\n", valueOrDash(strings.Join(bank.TLS.SPKIPins, ", ")))
+		}
+		if bank.ConfigError != "" {
+			fmt.Printf("Config error:         %s\n", bank.ConfigError)
+		}
+
+		return nil
+	},
+}
+
+var bankRefreshCmd = &cobra.Command{
+	Use:   "refresh <slug>",
+	Short: "Fetch the bank's public keys again from its Rapid URLs",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := newRegistryService(cmd).RefreshBank(args[0], bankRefreshRapidUrls)
+		if isNotRegistered(err) {
+			return err
+		}
+		if err != nil {
+			return &ExitError{Code: ExitUpstreamError, Err: err}
+		}
+
+		fmt.Printf("Bank %s public keys refreshed\n", args[0])
+		return nil
+	},
+}
+
+var bankRemoveCmd = &cobra.Command{
+	Use:   "remove <slug>",
+	Short: "Unregister a bank and delete its key directory",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryService := newRegistryService(cmd)
+
+		if _, err := registryService.ShowBank(args[0]); err != nil {
+			return err
+		}
+
+		question := fmt.Sprintf("Remove bank %s and delete its public keys?", args[0])
+		if manageKeepKeys {
+			question = fmt.Sprintf("Unregister bank %s, keeping its keys on disk?", args[0])
+		}
+		if err := confirm(manageAssumeYes, question); err != nil {
+			return err
+		}
+
+		if err := registryService.RemoveBank(args[0], manageKeepKeys); err != nil {
+			return err
+		}
+
+		fmt.Printf("Bank %s removed\n", args[0])
+		return nil
+	},
+}
+
+func isNotRegistered(err error) bool {
+	return ExitCode(err) == ExitNotFound
+}
+
+func init() {
+	bankListCmd.Flags().BoolVar(&manageOutputJSON, "json", false, "Print JSON instead of a table")
+	bankShowCmd.Flags().BoolVar(&manageOutputJSON, "json", false, "Print JSON instead of text")
+	bankRefreshCmd.Flags().StringSliceVar(&bankRefreshRapidUrls, "rapidUrl", nil, "Fetch from these Rapid URLs instead of the configured ones")
+	bankRemoveCmd.Flags().BoolVar(&manageAssumeYes, "yes", false, "Do not ask for confirmation")
+	bankRemoveCmd.Flags().BoolVar(&manageKeepKeys, "keep-keys", false, "Only unregister, leave the key directory on disk")
+
+	bankCmd.AddCommand(bankListCmd)
+	bankCmd.AddCommand(bankShowCmd)
+	bankCmd.AddCommand(bankRefreshCmd)
+	bankCmd.AddCommand(bankRemoveCmd)
+
+	RootCmd.AddCommand(bankCmd)
+}
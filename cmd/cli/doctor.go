@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/service"
+	"rapid-bridge/pkg/config"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorOutputJSON bool
+var doctorSkipNetwork bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the installation and report misconfigurations",
+	// a broken data directory is what this command is meant to diagnose, so
+	// it must not be loaded up front
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		doctorService := service.NewDoctorService(config.ResolveDataDir(dataDir, configFile), configFile, cmd.Flags().Changed("config"), constants.UpstreamProbeTimeout*time.Second, doctorSkipNetwork)
+		report := doctorService.Run()
+
+		if doctorOutputJSON {
+			if err := printJSON(report); err != nil {
+				return err
+			}
+		} else {
+			printDoctorReport(report)
+		}
+
+		if report.Failures > 0 {
+			return exitErrorf(ExitFailure, "%d checks failed", report.Failures)
+		}
+		return nil
+	},
+}
+
+func printDoctorReport(report service.DoctorReport) {
+	subject := ""
+	for _, check := range report.Checks {
+		if check.Subject != subject {
+			subject = check.Subject
+			fmt.Printf("\n%s\n", subject)
+		}
+
+		fmt.Printf("  [%-4s] %s\n", strings.ToUpper(string(check.Status)), check.Detail)
+		if check.Hint != "" {
+			fmt.Printf("         hint: %s\n", check.Hint)
+		}
+	}
+
+	fmt.Printf("\n%d checks, %d warnings, %d failures\n", len(report.Checks), report.Warnings, report.Failures)
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorOutputJSON, "json", false, "Print the report as JSON")
+	doctorCmd.Flags().StringVar(&configFile, "config", config.DefaultConfigFile, "Env file with SERVER_PORT and "+constants.DataDirEnv)
+	doctorCmd.Flags().BoolVar(&doctorSkipNetwork, "skip-network", false, "Do not test reachability of the banks' Rapid URLs")
+
+	RootCmd.AddCommand(doctorCmd)
+}
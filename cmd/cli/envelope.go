@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/security"
+	keymanagementfs "rapid-bridge/internal/adapter/keymanagement_fs"
+	securityadapter "rapid-bridge/internal/adapter/security"
+	"rapid-bridge/internal/dto/rapid"
+	"rapid-bridge/internal/service"
+	"rapid-bridge/internal/setup"
+
+	"github.com/spf13/cobra"
+)
+
+var envelopeInput string
+var envelopeFrom string
+var envelopeTo string
+var envelopeKeyVersion string
+var envelopeSealRaw bool
+
+var envelopeCmd = &cobra.Command{
+	Use:   "envelope",
+	Short: "Seal, open, sign, verify and inspect Rapid envelopes offline",
+	Long: `Reproduce outside the server what the bridge does to a message.
+
+Input is read from --in, or from stdin when --in is "-" or not given. Envelopes
+may be given either as a rapid resource request or as a rapid resource response
+with the envelope under "data". --from, --to and --key-version override the
+values found in the envelope.`,
+}
+
+var envelopeSealCmd = &cobra.Command{
+	Use:   "seal",
+	Short: "Encrypt an application request for a bank and sign it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if envelopeFrom == "" || envelopeTo == "" {
+			return exitErrorf(ExitUsage, "--from and --to are required")
+		}
+
+		payload, err := readEnvelopeInput()
+		if err != nil {
+			return err
+		}
+
+		envelopeService := newEnvelopeService(cmd)
+
+		var envelope rapid.RapidResourceRequest
+		if envelopeSealRaw {
+			envelope, err = envelopeService.SealRaw(envelopeFrom, envelopeTo, envelopeKeyVersion, payload)
+		} else {
+			envelope, err = envelopeService.Seal(envelopeFrom, envelopeTo, envelopeKeyVersion, payload)
+		}
+		if err != nil {
+			return err
+		}
+
+		return printJSON(envelope)
+	},
+}
+
+var envelopeOpenCmd = &cobra.Command{
+	Use:   "open",
+	Short: "Verify a bank's envelope and print the decrypted payload",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envelope, err := readEnvelope()
+		if err != nil {
+			return err
+		}
+
+		payload, err := newEnvelopeService(cmd).Open(envelope)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(payload))
+		return nil
+	},
+}
+
+var envelopeSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign an envelope's message with the sending application's key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envelope, err := readEnvelope()
+		if err != nil {
+			return err
+		}
+
+		envelope, err = newEnvelopeService(cmd).Sign(envelope)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(envelope)
+	},
+}
+
+var envelopeVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify an envelope's signature against its sender's public key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envelope, err := readEnvelope()
+		if err != nil {
+			return err
+		}
+
+		if err := newEnvelopeService(cmd).Verify(envelope); err != nil {
+			return err
+		}
+
+		fmt.Printf("Signature is valid for %s\n", envelope.From)
+		return nil
+	},
+}
+
+var envelopeInspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Decode an envelope's structure without any keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envelope, err := readEnvelope()
+		if err != nil {
+			return err
+		}
+
+		return printJSON(newEnvelopeService(cmd).Inspect(envelope))
+	},
+}
+
+func readEnvelopeInput() ([]byte, error) {
+	var data []byte
+	var err error
+	if envelopeInput == "" || envelopeInput == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(envelopeInput)
+	}
+	if err != nil {
+		return nil, exitErrorf(ExitUsage, "failed to read input: %v", err)
+	}
+	return data, nil
+}
+
+func readEnvelope() (rapid.RapidResourceRequest, error) {
+	data, err := readEnvelopeInput()
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	envelope, err := service.ParseEnvelope(data)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, &ExitError{Code: ExitUsage, Err: err}
+	}
+
+	if envelopeFrom != "" {
+		envelope.From = envelopeFrom
+	}
+	if envelopeTo != "" {
+		envelope.To = envelopeTo
+	}
+	if envelopeKeyVersion != "" {
+		envelope.KeyVersion = envelopeKeyVersion
+	}
+
+	return envelope, nil
+}
+
+func newEnvelopeService(cmd *cobra.Command) *service.EnvelopeService {
+	app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+
+	newSecurity := security.NewSecurity(securityadapter.NewHybridCryptography())
+
+	return service.NewEnvelopeService(keymanagementfs.NewFSKeyLoader(), *newSecurity, app.Config, app.Logger)
+}
+
+func init() {
+	envelopeCmd.PersistentFlags().StringVar(&envelopeInput, "in", "-", "Read input from this file, - for stdin")
+	envelopeCmd.PersistentFlags().StringVar(&envelopeFrom, "from", "", "Sender slug")
+	envelopeCmd.PersistentFlags().StringVar(&envelopeTo, "to", "", "Recipient slug")
+	envelopeCmd.PersistentFlags().StringVar(&envelopeKeyVersion, "key-version", "", "Application key version (defaults to the application's current one)")
+
+	envelopeSealCmd.Flags().BoolVar(&envelopeSealRaw, "raw", false, "Encrypt the input bytes as is instead of as an application request")
+
+	envelopeCmd.AddCommand(envelopeSealCmd)
+	envelopeCmd.AddCommand(envelopeOpenCmd)
+	envelopeCmd.AddCommand(envelopeSignCmd)
+	envelopeCmd.AddCommand(envelopeVerifyCmd)
+	envelopeCmd.AddCommand(envelopeInspectCmd)
+
+	RootCmd.AddCommand(envelopeCmd)
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"rapid-bridge/internal/service"
+)
+
+// Exit codes returned by the CLI so that scripts can tell failures apart.
+const (
+	ExitOK            = 0
+	ExitFailure       = 1  // unexpected failure
+	ExitUsage         = 2  // invalid flags, arguments or menu choice
+	ExitAborted       = 3  // refused to overwrite an existing registration, or not confirmed
+	ExitInputRequired = 4  // a prompt was needed in --non-interactive mode
+	ExitKeyError      = 5  // key generation, import or validation failed
+	ExitConfigError   = 6  // the configuration could not be read or saved
+	ExitUpstreamError = 7  // the bank could not be reached
+	ExitNotFound      = 8  // the application, bank or api key does not exist
+	ExitVerifyFailed  = 9  // an envelope or backup failed its signature, decryption or integrity check
+	ExitStartupError  = 10 // the server could not start listening
+)
+
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func exitErrorf(code int, format string, args ...any) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode maps an error returned by a command to the process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitError *ExitError
+	if errors.As(err, &exitError) {
+		return exitError.Code
+	}
+
+	if errors.Is(err, service.ErrApplicationNotRegistered) || errors.Is(err, service.ErrBankNotRegistered) || errors.Is(err, service.ErrAPIKeyNotFound) {
+		return ExitNotFound
+	}
+
+	if errors.Is(err, service.ErrSignatureInvalid) || errors.Is(err, service.ErrDecryptionFailed) || errors.Is(err, service.ErrBackupCorrupted) {
+		return ExitVerifyFailed
+	}
+
+	if errors.Is(err, service.ErrBackupKeysInvalid) {
+		return ExitKeyError
+	}
+
+	if errors.Is(err, service.ErrRegistryExists) {
+		return ExitAborted
+	}
+
+	return ExitFailure
+}
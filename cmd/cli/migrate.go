@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/migration"
+	"rapid-bridge/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateDryRun bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the data directory to the current schema version",
+	// loading the application fails on an outdated data directory, which is
+	// exactly what this command is meant to fix
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		resolvedDataDir := config.ResolveDataDir(dataDir, configFile)
+		migrator := migration.NewMigrator(resolvedDataDir)
+
+		current, steps, err := migrator.Plan()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Data directory: %s\n", resolvedDataDir)
+		fmt.Printf("Current schema version: %d\n", current)
+		fmt.Printf("Target schema version: %d\n", constants.DataSchemaVersion)
+
+		if len(steps) == 0 {
+			fmt.Println("\nData directory is up to date")
+			return nil
+		}
+
+		fmt.Println("\nPending migrations:")
+		for _, step := range steps {
+			fmt.Printf("  %d -> %d: %s\n", step.From, step.From+1, step.Description)
+		}
+
+		if migrateDryRun {
+			fmt.Println("\nDry run, no changes were made")
+			return nil
+		}
+
+		backupDir, err := migrator.Run()
+		if backupDir != "" {
+			fmt.Printf("\nBackup written to %s\n", backupDir)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Data directory migrated to schema version %d\n", constants.DataSchemaVersion)
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show the pending migrations without applying them")
+
+	RootCmd.AddCommand(migrateCmd)
+}
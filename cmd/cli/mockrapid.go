@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/setup"
+	"rapid-bridge/pkg/mockrapid"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var mockRapidListen string
+var mockRapidKeysDir string
+var mockRapidScenarios string
+
+var mockRapidCmd = &cobra.Command{
+	Use:   "mock-rapid",
+	Short: "Run a local Rapid Links simulator for development and tests",
+	Long: `Run a local Rapid Links simulator.
+
+It serves GET /public-key and POST ` + mockrapid.ResourcePrefix + `<route>, verifies and
+decrypts requests with the public keys of the applications registered in the
+data directory, and answers with scripted responses encrypted for and signed
+to the calling application. Register it as a bank with
+  rapid-bridge init bank --slug mock-bank --fetch --rapidUrl http://localhost:9000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+
+		keys, err := mockrapid.LoadOrGenerateBankKeys(mockRapidKeysDir)
+		if err != nil {
+			return &ExitError{Code: ExitKeyError, Err: err}
+		}
+
+		scenarios := mockrapid.DefaultScenarios()
+		if mockRapidScenarios != "" {
+			scenarios, err = mockrapid.LoadScenarios(mockRapidScenarios)
+			if err != nil {
+				return &ExitError{Code: ExitConfigError, Err: err}
+			}
+		}
+
+		server, err := mockrapid.New(mockrapid.Config{
+			Keys:         keys,
+			Applications: mockrapid.DataDirApplicationKeys{DataDir: app.Config.GetDataDir()},
+			Scenarios:    scenarios,
+			Logger:       app.Logger,
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		app.Logger.Info("Mock rapid listening", zap.String("address", mockRapidListen), zap.String("keys_dir", mockRapidKeysDir))
+		fmt.Printf("Mock Rapid Links listening on %s\n", mockRapidListen)
+
+		if err := server.Start(mockRapidListen); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	mockRapidCmd.Flags().StringVar(&mockRapidListen, "listen", ":9000", "Address to listen on")
+	mockRapidCmd.Flags().StringVar(&mockRapidKeysDir, "keys-dir", "_mock_rapid_data", "Directory holding the simulated bank's keys, generated on first use")
+	mockRapidCmd.Flags().StringVar(&mockRapidScenarios, "scenarios", "", "JSON file of per-route scenarios merged over the defaults")
+
+	RootCmd.AddCommand(mockRapidCmd)
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+)
+
+// promptChoice prints a numbered menu and returns the chosen option, starting
+// at 1. In non-interactive mode it fails instead of reading stdin.
+func promptChoice(nonInteractive bool, question string, options ...string) (int, error) {
+	if nonInteractive {
+		return 0, exitErrorf(ExitInputRequired, "input required in non-interactive mode: %s", question)
+	}
+
+	fmt.Println("\n" + question)
+	for i, option := range options {
+		fmt.Printf("%d) %s\n", i+1, option)
+	}
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	if choice < 1 || choice > len(options) {
+		return 0, exitErrorf(ExitUsage, "invalid choice")
+	}
+
+	return choice, nil
+}
+
+// promptPath asks for a file path unless one was already given as a flag.
+func promptPath(nonInteractive bool, label, flagName, value string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+
+	if nonInteractive {
+		return "", exitErrorf(ExitInputRequired, "--%s is required in non-interactive mode", flagName)
+	}
+
+	fmt.Printf("%s: ", label)
+	fmt.Scanln(&value)
+
+	if value == "" {
+		return "", exitErrorf(ExitUsage, "%s is required", label)
+	}
+
+	return value, nil
+}
+
+// confirm asks a yes/no question unless the answer was given with --yes.
+func confirm(assumeYes bool, question string) error {
+	if assumeYes {
+		return nil
+	}
+
+	fmt.Printf("%s [y/N]: ", question)
+
+	var answer string
+	fmt.Scanln(&answer)
+
+	if answer != "y" && answer != "Y" && answer != "yes" {
+		return exitErrorf(ExitAborted, "aborted")
+	}
+
+	return nil
+}
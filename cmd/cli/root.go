@@ -4,21 +4,38 @@ import (
 	"context"
 	"log"
 	"os"
-	server "rapid-bridge/cmd/server"
 	"rapid-bridge/constants"
+	adapterconfig "rapid-bridge/internal/adapter/config"
 	"rapid-bridge/internal/setup"
+	"rapid-bridge/pkg/config"
 
 	"github.com/spf13/cobra"
 )
 
+var dataDir string
+
 var RootCmd = &cobra.Command{
 	Use:   "rapid-bridge",
 	Short: "Rapid Bridge CLI - Backend utility",
 	Long:  `Rapid Bridge is a CLI tool for backend initialization and management.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		app := setup.NewCLIApplication()
+	// errors are already reported with a meaningful exit code, the usage
+	// text would only bury them
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		resolvedDataDir := config.ResolveDataDir(dataDir, configFile)
+		// commands that only read never create a data directory
+		if cmd.Parent() == initCmd || cmd == serveCmd {
+			if err := adapterconfig.BootstrapDataDir(resolvedDataDir); err != nil {
+				return &ExitError{Code: ExitConfigError, Err: err}
+			}
+		}
+		app, err := setup.LoadCLIApplication(resolvedDataDir)
+		if err != nil {
+			return &ExitError{Code: ExitConfigError, Err: err}
+		}
 		ctx := context.WithValue(cmd.Context(), constants.Application, app)
 		cmd.SetContext(ctx)
+		return nil
 	},
 }
 
@@ -29,10 +46,15 @@ var initCmd = &cobra.Command{
 }
 
 func init() {
+	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &ExitError{Code: ExitUsage, Err: err}
+	})
+
+	RootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "Data directory (defaults to $"+constants.DataDirEnv+" or "+constants.RapidBridgeData+")")
 
 	initCmd.AddCommand(initAppCmd)
 	initCmd.AddCommand(initBankCmd)
-	initCmd.AddCommand(server.InitServerCmd)
+	initCmd.AddCommand(initServerCmd)
 
 	RootCmd.AddCommand(initCmd)
 }
@@ -40,6 +62,6 @@ func init() {
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		log.Println(err)
-		os.Exit(1)
+		os.Exit(ExitCode(err))
 	}
 }
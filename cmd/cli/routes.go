@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/adapter/config"
+	"rapid-bridge/internal/setup"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var routesOutputJSON bool
+
+var routesCmd = &cobra.Command{
+	Use:   "routes",
+	Short: "Validate and list the resource route table",
+	Long: `Validate and list the resource routes the server forwards to Rapid Links.
+
+Routes are read from ` + constants.RoutesConfigFile + ` in the data directory, or are the built-in
+defaults when there is none. A running server picks up changes to the file
+without a restart; run this command first, since an invalid file is ignored
+by a running server and refused by a starting one.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+		dataDir := app.Config.GetDataDir()
+
+		routeTable, err := config.LoadRouteTable(dataDir, app.Logger)
+		if err != nil {
+			return &ExitError{Code: ExitConfigError, Err: err}
+		}
+		routes := routeTable.Routes()
+
+		if routesOutputJSON {
+			routeConfigs := []config.RouteConfig{}
+			for _, route := range routes {
+				routeConfigs = append(routeConfigs, config.RouteConfig{
+					Method:       route.Method,
+					Path:         route.Path,
+					UpstreamPath: route.UpstreamPath,
+					Timeout:      route.Timeout.String(),
+					AllowedBanks: route.AllowedBanks,
+					Idempotent:   &route.Idempotent,
+				})
+			}
+			return printJSON(config.RoutesFile{Routes: routeConfigs})
+		}
+
+		rows := [][]string{}
+		for _, route := range routes {
+			rows = append(rows, []string{
+				route.Method,
+				strings.TrimSuffix(constants.ResourcePathPrefix, "/") + route.Path,
+				route.UpstreamPath,
+				route.Timeout.String(),
+				valueOrDash(strings.Join(route.AllowedBanks, ", ")),
+				strconv.FormatBool(route.Idempotent),
+			})
+		}
+		printTable([]string{"METHOD", "PATH", "UPSTREAM PATH", "TIMEOUT", "BANKS", "IDEMPOTENT"}, rows)
+
+		if _, err := os.Stat(filepath.Join(dataDir, constants.RoutesConfigFile)); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "\nno %s in %s, these are the default routes\n", constants.RoutesConfigFile, dataDir)
+		}
+		return nil
+	},
+}
+
+func init() {
+	routesCmd.Flags().BoolVar(&routesOutputJSON, "json", false, "Print JSON instead of a table")
+
+	RootCmd.AddCommand(routesCmd)
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/service"
+	"rapid-bridge/internal/setup"
+	"rapid-bridge/pkg/config"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var sendFrom string
+var sendTo string
+var sendKeyVersion string
+var sendData string
+var sendBridgeUrl string
+var sendRaw bool
+var sendMethod string
+var sendAPIKey string
+
+var sendCmd = &cobra.Command{
+	Use:   "send <route>",
+	Short: "Send a plaintext request through a running bridge",
+	Long: `Send a plaintext application request through a running bridge and print the
+decrypted response.
+
+The route is the path after ` + constants.ResourcePathPrefix + `, e.g. balance or payment/initiate,
+and may carry a query string. --key-version defaults to the application's current key version. The API key
+is taken from --api-key or $` + constants.APIKeyEnv + `. With --raw the
+request is sealed locally and sent straight to the bank's Rapid URLs, and both
+envelopes are printed next to the decrypted payload.`,
+	Example: `  rapid-bridge send balance --from my-app --to my-bank --data @balance.json
+  echo '{"message":"hi"}' | rapid-bridge send balance --from my-app --to my-bank --data @-
+  rapid-bridge send 'transaction/status?id=42' --method GET --from my-app --to my-bank`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+
+		method := strings.ToUpper(sendMethod)
+
+		body, err := readSendData(sendData)
+		if err != nil {
+			return err
+		}
+
+		sendService := service.NewSendService(newEnvelopeService(cmd), app.Config, app.Logger)
+
+		if sendRaw {
+			result, err := sendService.SendRaw(method, args[0], sendFrom, sendTo, sendKeyVersion, body)
+			if err != nil {
+				return sendError(err)
+			}
+			return printRawSendResult(result)
+		}
+
+		apiKey := sendAPIKey
+		if apiKey == "" {
+			apiKey = os.Getenv(constants.APIKeyEnv)
+		}
+
+		result, err := sendService.Send(config.ResolveBridgeUrl(sendBridgeUrl), apiKey, method, args[0], sendFrom, sendTo, sendKeyVersion, body)
+		if err != nil {
+			return sendError(err)
+		}
+
+		fmt.Println(prettyJSON(result.Body))
+		fmt.Fprintf(os.Stderr, "HTTP %d %s in %s, key version %s\n", result.StatusCode, http.StatusText(result.StatusCode), result.Duration.Round(time.Millisecond), result.KeyVersion)
+
+		if result.StatusCode >= 300 {
+			return exitErrorf(ExitFailure, "bridge answered HTTP %d", result.StatusCode)
+		}
+		return nil
+	},
+}
+
+// readSendData reads --data: @file, @- for stdin, or the JSON itself.
+func readSendData(data string) ([]byte, error) {
+	if data == "" {
+		return []byte("{}"), nil
+	}
+	if !strings.HasPrefix(data, "@") {
+		return []byte(data), nil
+	}
+
+	var content []byte
+	var err error
+	if data == "@-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(strings.TrimPrefix(data, "@"))
+	}
+	if err != nil {
+		return nil, exitErrorf(ExitUsage, "failed to read --data: %v", err)
+	}
+	return content, nil
+}
+
+// sendError maps errors from reaching the bridge or the bank to the
+// upstream exit code, leaving registry and key errors as they are.
+func sendError(err error) error {
+	if ExitCode(err) != ExitFailure {
+		return err
+	}
+	return &ExitError{Code: ExitUpstreamError, Err: err}
+}
+
+func printRawSendResult(result service.SendResult) error {
+	fmt.Printf("Request envelope (key version %s):\n", result.KeyVersion)
+	if err := printJSON(result.RequestEnvelope); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nUpstream response from %s: HTTP %d %s in %s\n", result.RapidUrl, result.StatusCode, http.StatusText(result.StatusCode), result.Duration.Round(time.Millisecond))
+	fmt.Println(prettyJSON(result.Body))
+
+	if result.OpenError != "" {
+		fmt.Printf("\nResponse could not be opened: %s\n", result.OpenError)
+		return exitErrorf(ExitVerifyFailed, "response could not be opened")
+	}
+
+	fmt.Println("\nDecrypted payload:")
+	fmt.Println(prettyJSON(result.Payload))
+
+	if result.StatusCode >= 300 {
+		return exitErrorf(ExitFailure, "rapid links answered HTTP %d", result.StatusCode)
+	}
+	return nil
+}
+
+// prettyJSON indents JSON and returns anything else unchanged.
+func prettyJSON(data []byte) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return strings.TrimSpace(string(data))
+	}
+	return strings.TrimSpace(indented.String())
+}
+
+func init() {
+	sendCmd.Flags().StringVar(&sendFrom, "from", "", "Source application slug (required)")
+	sendCmd.Flags().StringVar(&sendTo, "to", "", "Destination bank slug (required)")
+	sendCmd.Flags().StringVar(&sendKeyVersion, "key-version", "", "Application key version (defaults to the application's current one)")
+	sendCmd.Flags().StringVar(&sendData, "data", "", "Request body: JSON, @file.json, or @- for stdin (defaults to {})")
+	sendCmd.Flags().StringVar(&sendBridgeUrl, "bridge", "", "Bridge url (defaults to http://localhost with SERVER_PORT from the environment or .env)")
+	sendCmd.Flags().StringVar(&sendMethod, "method", http.MethodPost, "HTTP method of the resource route")
+	sendCmd.Flags().StringVar(&sendAPIKey, "api-key", "", "API key of the application (defaults to $"+constants.APIKeyEnv+")")
+	sendCmd.Flags().BoolVar(&sendRaw, "raw", false, "Bypass the bridge and show the envelopes exchanged with Rapid Links")
+
+	sendCmd.MarkFlagRequired("from")
+	sendCmd.MarkFlagRequired("to")
+
+	RootCmd.AddCommand(sendCmd)
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	server "rapid-bridge/cmd/server"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/route"
+	"rapid-bridge/internal/setup"
+	"rapid-bridge/pkg/config"
+	"rapid-bridge/pkg/security/tlsconfig"
+	"rapid-bridge/pkg/tracing"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var configFile = config.DefaultConfigFile
+var serveListen string
+var serveShutdownTimeout time.Duration
+var serveAuth string
+var serveTLSCert string
+var serveTLSKey string
+var serveTLSClientCA string
+var serveTLSMinVersion string
+var serveMTLSCommonNameFallback bool
+var serveIdempotencyWindow time.Duration
+var serveMetricsListen string
+var serveTraceExporter string
+var serveOTLPEndpoint string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the bridge server",
+	Long: `Run the bridge server.
+
+The listen address is taken from --listen, or from SERVER_PORT in the
+environment or the --config env file. On SIGINT or SIGTERM the server stops
+accepting connections and waits up to --shutdown-timeout for in-flight requests.
+
+Resource requests must carry an API key of the calling application, issued
+with "rapid-bridge app key create". --auth none turns this off.
+
+With --tls-cert and --tls-key the server speaks HTTPS, and with --tls-client-ca
+it also requires client certificates. --auth mtls then maps each certificate to
+an application with ` + constants.ClientIdentitiesFile + ` in the data directory instead of
+trusting ` + constants.From + `. Certificates without an entry are rejected, unless
+--mtls-cn-fallback maps them to the application named by their common name.
+Renewed certificates and CA bundles are picked up without a restart.
+
+A resource request with an ` + constants.IdempotencyKey + ` header is forwarded once. Repeating it
+within --idempotency-window replays the stored response instead.
+
+With --metrics-listen, Prometheus metrics are served on /metrics of a separate
+plain HTTP listener, which should not be reachable by applications.
+
+With --trace-exporter otlp or stdout, each request is traced with OpenTelemetry
+from the handler through key loading, encryption, signing, the call to Rapid
+Links, verification and decryption. The W3C traceparent is passed on to Rapid
+Links. --otlp-endpoint points at the collector, which otherwise is taken from
+OTEL_EXPORTER_OTLP_ENDPOINT or is http://localhost:4318.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var initServerCmd = &cobra.Command{
+	Use:        "server",
+	Short:      "Run the bridge server (deprecated, use serve)",
+	Deprecated: "use \"rapid-bridge serve\" instead",
+	Args:       cobra.NoArgs,
+	RunE:       runServe,
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	app := cmd.Context().Value(constants.Application).(*setup.CLIApplication)
+
+	// the env file is optional when the address is given on the command line
+	// and was not asked for explicitly
+	optional := serveListen != "" && !cmd.Flags().Changed("config")
+	cfg, err := config.LoadConfigFile(configFile, optional)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: err}
+	}
+
+	listen := serveListen
+	if listen == "" {
+		listen = cfg.ServerPort
+	}
+	if listen == "" {
+		return exitErrorf(ExitConfigError, "no listen address: pass --listen or set SERVER_PORT in %s", configFile)
+	}
+	// SERVER_PORT=8080 means all interfaces, as :8080 does
+	if !strings.Contains(listen, ":") {
+		listen = ":" + listen
+	}
+
+	if serveAuth != route.AuthAPIKey && serveAuth != route.AuthMTLS && serveAuth != route.AuthNone {
+		return exitErrorf(ExitUsage, "invalid --auth %q, use %s, %s or %s", serveAuth, route.AuthAPIKey, route.AuthMTLS, route.AuthNone)
+	}
+
+	if serveMTLSCommonNameFallback && serveAuth != route.AuthMTLS {
+		return exitErrorf(ExitUsage, "--mtls-cn-fallback needs --auth %s", route.AuthMTLS)
+	}
+
+	if serveIdempotencyWindow < 0 {
+		return exitErrorf(ExitUsage, "--idempotency-window must not be negative")
+	}
+
+	switch serveTraceExporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		return exitErrorf(ExitUsage, "invalid --trace-exporter %q, use %s, %s or %s", serveTraceExporter, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout)
+	}
+	if serveOTLPEndpoint != "" && serveTraceExporter != tracing.ExporterOTLP {
+		return exitErrorf(ExitUsage, "--otlp-endpoint needs --trace-exporter %s", tracing.ExporterOTLP)
+	}
+
+	tlsOptions, err := serveTLSOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// a second signal kills the process instead of waiting for the drain
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = server.Serve(ctx, server.Options{
+		DataDir:         app.Config.GetDataDir(),
+		Listen:          listen,
+		ShutdownTimeout: serveShutdownTimeout,
+		Auth:            serveAuth,
+		TLS:             tlsOptions,
+
+		MTLSCommonNameFallback: serveMTLSCommonNameFallback,
+
+		IdempotencyWindow: serveIdempotencyWindow,
+		MetricsListen:     serveMetricsListen,
+		Tracing:           tracing.Options{Exporter: serveTraceExporter, OTLPEndpoint: serveOTLPEndpoint},
+	})
+	if errors.Is(err, server.ErrStartup) {
+		return &ExitError{Code: ExitStartupError, Err: err}
+	}
+	return err
+}
+
+func serveTLSOptions() (*tlsconfig.ServerOptions, error) {
+	if (serveTLSCert == "") != (serveTLSKey == "") {
+		return nil, exitErrorf(ExitUsage, "--tls-cert and --tls-key must be given together")
+	}
+	if serveTLSCert == "" {
+		if serveTLSClientCA != "" {
+			return nil, exitErrorf(ExitUsage, "--tls-client-ca needs --tls-cert and --tls-key")
+		}
+		if serveAuth == route.AuthMTLS {
+			return nil, exitErrorf(ExitUsage, "--auth %s needs --tls-cert, --tls-key and --tls-client-ca", route.AuthMTLS)
+		}
+		return nil, nil
+	}
+	if serveAuth == route.AuthMTLS && serveTLSClientCA == "" {
+		return nil, exitErrorf(ExitUsage, "--auth %s needs --tls-client-ca", route.AuthMTLS)
+	}
+
+	minVersion, err := tlsconfig.ParseVersion(serveTLSMinVersion)
+	if err != nil {
+		return nil, &ExitError{Code: ExitUsage, Err: err}
+	}
+
+	return &tlsconfig.ServerOptions{
+		CertFile:     serveTLSCert,
+		KeyFile:      serveTLSKey,
+		ClientCAFile: serveTLSClientCA,
+		MinVersion:   minVersion,
+	}, nil
+}
+
+func init() {
+	for _, command := range []*cobra.Command{serveCmd, initServerCmd} {
+		command.Flags().StringVar(&serveListen, "listen", "", "Address to listen on, e.g. :8080 (defaults to SERVER_PORT)")
+		command.Flags().StringVar(&configFile, "config", config.DefaultConfigFile, "Env file with SERVER_PORT and "+constants.DataDirEnv)
+		command.Flags().StringVar(&serveAuth, "auth", route.AuthAPIKey, "How callers authenticate: api-key, mtls, or none to trust "+constants.From)
+		command.Flags().StringVar(&serveTLSCert, "tls-cert", "", "PEM certificate to serve HTTPS with")
+		command.Flags().StringVar(&serveTLSKey, "tls-key", "", "PEM private key of --tls-cert")
+		command.Flags().StringVar(&serveTLSClientCA, "tls-client-ca", "", "PEM bundle of CAs to require client certificates from")
+		command.Flags().BoolVar(&serveMTLSCommonNameFallback, "mtls-cn-fallback", false, "With --auth mtls, map certificates without an entry in "+constants.ClientIdentitiesFile+" to the application named by their common name")
+		command.Flags().StringVar(&serveTLSMinVersion, "tls-min-version", "1.2", "Lowest TLS version to accept: 1.2 or 1.3")
+		command.Flags().DurationVar(&serveIdempotencyWindow, "idempotency-window", constants.IdempotencyWindow*time.Hour, "How long responses to requests with an "+constants.IdempotencyKey+" are replayed, 0 to ignore the header")
+		command.Flags().StringVar(&serveMetricsListen, "metrics-listen", "", "Address to serve Prometheus metrics on, e.g. 127.0.0.1:9090 (none when empty)")
+		command.Flags().StringVar(&serveTraceExporter, "trace-exporter", tracing.ExporterNone, "Where to export OpenTelemetry spans: none, otlp or stdout")
+		command.Flags().StringVar(&serveOTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector to export spans to, e.g. http://localhost:4318")
+		command.Flags().DurationVar(&serveShutdownTimeout, "shutdown-timeout", constants.ShutdownTimeout*time.Second, "How long to wait for in-flight requests on shutdown")
+	}
+
+	RootCmd.AddCommand(serveCmd)
+}
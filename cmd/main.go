@@ -1,13 +1,13 @@
 package main
 
 import (
-	"log"
+	"os"
 	"rapid-bridge/cmd/cli"
 )
 
 func main() {
 
 	if err := cli.RootCmd.Execute(); err != nil {
-		log.Fatalf("Error: %v", err)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -1,42 +1,89 @@
 package server
 
 import (
-	"go.uber.org/zap"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"rapid-bridge/internal/adapter/config"
+	"rapid-bridge/internal/handler"
 	"rapid-bridge/internal/route"
 	"rapid-bridge/internal/setup"
-	"rapid-bridge/pkg/config"
+	"rapid-bridge/pkg/metrics"
+	"rapid-bridge/pkg/security/tlsconfig"
+	"rapid-bridge/pkg/tracing"
 	"rapid-bridge/pkg/util"
+	"time"
 
 	rmiddleware "rapid-bridge/pkg/middleware"
 
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	"github.com/labstack/echo/v4"
 )
 
-var InitServerCmd = &cobra.Command{
-	Use:   "server",
-	Short: "Initialize backend server configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		StartServer()
-	},
+// ErrStartup is wrapped by Serve errors that happened before the server was
+// accepting connections.
+var ErrStartup = errors.New("server failed to start")
+
+type Options struct {
+	DataDir string
+	Listen  string
+	// how long in-flight requests may take to finish after a shutdown signal
+	ShutdownTimeout time.Duration
+	// route.AuthAPIKey, route.AuthMTLS, or route.AuthNone to trust
+	// X-Source-Slug as before
+	Auth string
+	// serve HTTPS when set, reloading the files when they change
+	TLS *tlsconfig.ServerOptions
+	// with AuthMTLS, map certificates without an entry in
+	// client_identities.json to their subject common name
+	MTLSCommonNameFallback bool
+	// how long responses to requests with an Idempotency-Key are replayed, 0
+	// to ignore the header
+	IdempotencyWindow time.Duration
+	// address of the plain HTTP listener serving /metrics, none when empty
+	MetricsListen string
+	// where spans are exported to
+	Tracing tracing.Options
 }
 
-func StartServer() {
+// Serve runs the bridge until ctx is cancelled, then stops accepting
+// connections and waits up to ShutdownTimeout for in-flight requests.
+func Serve(ctx context.Context, options Options) error {
 
-	app := setup.NewApplication()
+	app, err := setup.LoadApplication(options.DataDir)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStartup, err)
+	}
 	defer app.Logger.Sync()
 
-	config, err := config.LoadConfig()
+	shutdownTracing, err := tracing.Setup(ctx, options.Tracing)
 	if err != nil {
-		app.Logger.Fatal("Failed to load config", zap.Error(err))
+		app.Logger.Error("Tracing failed to start", zap.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrStartup, err)
 	}
+	defer func() {
+		// spans of the last requests are exported before the process exits
+		flushCtx, cancel := context.WithTimeout(context.Background(), options.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			app.Logger.Error("Spans could not be exported on shutdown", zap.String("error", err.Error()))
+		}
+	}()
 
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
 	e.Validator = util.NewCustomValidator()
+	e.HTTPErrorHandler = handler.NewErrorHandler(app.Logger)
 
+	// first, so that every log line and response of the request has its id
+	e.Use(rmiddleware.RequestIDMiddleware())
+	e.Use(rmiddleware.TracingMiddleware(app.Routes))
 	e.Use(middleware.Secure())
 	e.Use(middleware.RemoveTrailingSlash())
 	e.Use(rmiddleware.CreateEchoLogger(app.Logger))
@@ -45,8 +92,87 @@ func StartServer() {
 		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch, http.MethodOptions},
 	}))
 
-	route.SetupRoutes(e, app)
+	if options.Auth == route.AuthNone {
+		app.Logger.Warn("Authentication is disabled, any client can act as any registered application")
+	}
+	routeOptions := route.Options{Auth: options.Auth, IdempotencyWindow: options.IdempotencyWindow}
+	if options.Auth == route.AuthMTLS {
+		clientIdentities, err := config.LoadClientIdentities(options.DataDir, options.MTLSCommonNameFallback, app.Logger)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrStartup, err)
+		}
+		routeOptions.ClientIdentities = clientIdentities
+	}
+	stopRoutes := route.SetupRoutes(e, app, routeOptions)
+	// after the drain, so that the last requests still have them
+	defer stopRoutes()
+
+	// binding before serving makes a taken or invalid address a startup
+	// error rather than something only visible in the logs
+	listener, err := net.Listen("tcp", options.Listen)
+	if err != nil {
+		app.Logger.Error("Server failed to start", zap.String("listen", options.Listen), zap.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", ErrStartup, err)
+	}
+	if options.TLS != nil {
+		reloader, err := tlsconfig.NewServerReloader(*options.TLS, app.Logger)
+		if err != nil {
+			listener.Close()
+			app.Logger.Error("Server failed to start", zap.String("error", err.Error()))
+			return fmt.Errorf("%w: %w", ErrStartup, err)
+		}
+		listener = tls.NewListener(listener, reloader.TLSConfig())
+	}
+	e.Listener = listener
+
+	if options.MetricsListen != "" {
+		metricsListener, err := net.Listen("tcp", options.MetricsListen)
+		if err != nil {
+			listener.Close()
+			app.Logger.Error("Metrics listener failed to start", zap.String("listen", options.MetricsListen), zap.String("error", err.Error()))
+			return fmt.Errorf("%w: %w", ErrStartup, err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		// closed last, so that scrapes go on while requests drain
+		defer metricsServer.Close()
+		go func() {
+			if err := metricsServer.Serve(metricsListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				app.Logger.Error("Metrics listener stopped", zap.String("error", err.Error()))
+			}
+		}()
+		app.Logger.Info("Serving metrics", zap.String("listen", options.MetricsListen))
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- e.Start(options.Listen)
+	}()
+
+	app.Logger.Info("Server started successfully", zap.String("listen", options.Listen), zap.String("data_dir", options.DataDir), zap.Bool("tls", options.TLS != nil), zap.String("trace_exporter", options.Tracing.Exporter))
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		app.Logger.Error("Server stopped", zap.String("error", err.Error()))
+		return err
+	case <-ctx.Done():
+	}
+
+	app.Logger.Info("Shutting down, draining in-flight requests", zap.String("timeout", options.ShutdownTimeout.String()))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), options.ShutdownTimeout)
+	defer cancel()
+
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		app.Logger.Error("In-flight requests did not finish before the shutdown deadline", zap.String("error", err.Error()))
+		return fmt.Errorf("graceful shutdown did not complete: %w", err)
+	}
 
-	app.Logger.Info("Server started successfully")
-	e.Start(config.ServerPort)
+	app.Logger.Info("Server stopped")
+	return nil
 }
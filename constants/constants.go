@@ -1,18 +1,51 @@
 package constants
 
+// RequestId is the log field of the request id, which travels in the
+// X-Request-ID header.
 const RequestId = "request_id"
+const RequestIDMaxLength = 128
+
+// Forwarded is set on the echo context once a request may have reached the
+// bank.
+const Forwarded = "forwarded"
 
 const From = "X-Source-Slug"
 const To = "X-Destination-Slug"
 const RapidUrl = "X-Rapid-Url"
 const KeyVersion = "X-Key-Version"
 
+// UpstreamStatus carries the status the bank's Rapid Links answered with.
+const UpstreamStatus = "X-Upstream-Status"
+
+// BridgeVersion tells Rapid Links which bridge version forwarded a request.
+const BridgeVersion = "X-Rapid-Bridge-Version"
+
+// Version of the bridge, set at build time with
+// -ldflags "-X rapid-bridge/constants.Version=<version>".
+var Version = "dev"
+
 const Application = "application"
 const Bank = "bank"
 
 const ApplicationUlid = "application_ulid"
 
+// ResourcePathPrefix is where the bridge serves the resource routes, and the
+// path it forwards them to on Rapid Links.
+const ResourcePathPrefix = "/api/v1/resource/"
+
+// RapidBridgeData is the default data directory, used when neither the
+// --data-dir flag, the RAPID_BRIDGE_DATA_DIR environment variable nor the
+// .env file specify one.
 const RapidBridgeData = "./_rapid_bridge_data"
+const DataDirEnv = "RAPID_BRIDGE_DATA_DIR"
+const BackupPassphraseEnv = "RAPID_BRIDGE_BACKUP_PASSPHRASE"
+const APIKeyEnv = "RAPID_BRIDGE_API_KEY"
+const CoreConfigFile = "core.json"
+const SchemaVersionFile = "schema.json"
+
+// DataSchemaVersion is the data directory layout version this build reads
+// and writes. Bump it together with a new step in internal/migration.
+const DataSchemaVersion = 3
 
 const RSAKeyBitSize = 4096
 
@@ -23,3 +56,45 @@ const RSAPrivateKeyFile = "rsa_private_key.pem"
 const RSAPublicKeyFile = "rsa_public_key.pem"
 const Ed25519PrivateKeyFile = "ed25519_private_key.pem"
 const Ed25519PublicKeyFile = "ed25519_public_key.pem"
+
+const APIKeysFile = "api_keys.json"
+
+const UpstreamHealthCheckInterval = 10 // in seconds
+const UpstreamProbeTimeout = 3         // in seconds
+
+const UpstreamConnectTimeout = 5       // in seconds
+const UpstreamTLSHandshakeTimeout = 10 // in seconds
+// UpstreamRequestTimeout bounds calls that have no route timeout, such as
+// fetching a bank's public keys.
+const UpstreamRequestTimeout = 30 // in seconds
+
+// retries of idempotent routes, after the first attempt
+const UpstreamRetries = 2
+const UpstreamRetryBaseDelay = 200 // in milliseconds
+const UpstreamRetryMaxDelay = 2000 // in milliseconds
+
+const CircuitBreakerThreshold = 5 // consecutive failed requests
+const CircuitBreakerCooldown = 30 // in seconds
+
+const ShutdownTimeout = 30 // in seconds
+
+// RoutesConfigFile in the data directory overrides the default resource routes.
+const RoutesConfigFile = "routes.json"
+const DefaultRouteTimeout = 30 // in seconds
+
+// LimitsConfigFile in the data directory holds the rate limits and
+// concurrency caps of applications, banks and routes.
+const LimitsConfigFile = "limits.json"
+
+// ClientIdentitiesFile in the data directory maps client certificate
+// identities to applications when the server runs with --auth mtls.
+const ClientIdentitiesFile = "client_identities.json"
+
+// IdempotencyKey is the request header that makes retrying a resource request
+// safe; responses are kept in IdempotencyDir below the data directory.
+const IdempotencyKey = "Idempotency-Key"
+const IdempotencyDir = "idempotency"
+const IdempotencyWindow = 24         // in hours
+const IdempotencyPendingTimeout = 10 // in minutes, after which an unfinished request is given up
+const IdempotencyPurgeInterval = 10  // in minutes
+const IdempotencyMaxKeyLength = 255
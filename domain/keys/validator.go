@@ -82,3 +82,15 @@ func validatePublicKey(publicKey any) error {
 		return fmt.Errorf("unsupported public key type: %T", key)
 	}
 }
+
+// PairMatches reports whether publicKey belongs to privateKey.
+func PairMatches(privateKey, publicKey any) bool {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return key.PublicKey.Equal(publicKey)
+	case ed25519.PrivateKey:
+		return key.Public().(ed25519.PublicKey).Equal(publicKey)
+	default:
+		return false
+	}
+}
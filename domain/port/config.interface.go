@@ -7,18 +7,37 @@ import (
 )
 
 type ServerConfig interface {
+	GetDataDir() string
 	GetRapidLinksUrl() string
+	GetBankRapidUrls(bankSlug string) ([]string, bool)
+	GetBankTLS(bankSlug string) *UpstreamTLS
+	// GetBankForwardHeaders returns the application headers forwarded to the
+	// bank, the global forward_headers followed by the bank's own
+	GetBankForwardHeaders(bankSlug string) []string
+	GetRegisteredBanks() []string
+	GetRegisteredApplications() []string
+	IsApplicationRegistered(applicationSlug string) bool
+	IsApplicationDisabled(applicationSlug string) bool
 }
 
 type CLIConfig interface {
+	GetDataDir() string
+
 	GetRegisteredBanks() []string
 	GetRegisteredApplications() []string
 
+	GetDisabledApplications() []string
+
 	GetApplicationDetails(applicationSlug string) *CLIApplicationDetails
+	LoadApplicationDetails(applicationSlug string) (*CLIApplicationDetails, error)
+	LoadBankDetails(bankSlug string) (*CLIBankDetails, error)
 
 	AddBankSlug(bankSlug string)
 	AddRegisteredBanks(bankSlug string)
 	AddBankKeysPaths(rsaPublicKeyPath string, ed25519PublicKeyPath string)
+	AddBankRapidUrls(rapidUrls []string)
+	AddBankTLS(upstreamTLS *UpstreamTLS)
+	AddBankForwardHeaders(headers []string)
 
 	AddRegisteredApplications(applicationSlug string)
 	AddApplicationSlug(applicationSlug string)
@@ -26,6 +45,11 @@ type CLIConfig interface {
 	AddApplicationKeysPaths(rsaPrivateKeyPath string, rsaPublicKeyPath string, ed25519PrivateKeyPath string, ed25519PublicKeyPath string)
 	AddKeysValidityPeriod(encryptionKeyValidityPeriod, signingKeyValidityPeriod int)
 
+	DisableApplication(applicationSlug string)
+	EnableApplication(applicationSlug string)
+	RemoveRegisteredApplication(applicationSlug string)
+	RemoveRegisteredBank(bankSlug string)
+
 	SaveApplicationConfigToFile() error
 	SaveBankConfigToFile() error
 
@@ -69,3 +93,12 @@ type CLIApplicationDetails struct {
 	Slug       string `json:"slug" mapstructure:"slug"`
 	KeyVersion string `json:"key_version" mapstructure:"key_version"`
 }
+
+type CLIBankDetails struct {
+	RSAPublicKeyPath     string       `json:"rsa_public_key_path"`
+	Ed25519PublicKeyPath string       `json:"ed25519_public_key_path"`
+	RapidUrls            []string     `json:"rapid_urls"`
+	TLS                  *UpstreamTLS `json:"tls,omitempty"`
+	ForwardHeaders       []string     `json:"forward_headers,omitempty"`
+	Slug                 string       `json:"slug"`
+}
@@ -0,0 +1,34 @@
+package port
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// APIKey is an application credential as stored at rest: only the SHA-256
+// hash of the secret is kept.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CredentialStore interface {
+	LoadAPIKeys(applicationSlug string) ([]APIKey, error)
+	SaveAPIKeys(applicationSlug string, apiKeys []APIKey) error
+}
+
+// ApplicationAuthenticator checks that a caller holds a credential of the
+// application it claims to be.
+type ApplicationAuthenticator interface {
+	AuthenticateAPIKey(applicationSlug, apiKey string) error
+}
+
+// ClientIdentityResolver maps a verified client certificate to the
+// application it was issued to.
+type ClientIdentityResolver interface {
+	ApplicationForCertificate(certificate *x509.Certificate) (string, bool)
+}
@@ -10,6 +10,8 @@ type EncryptionDecryptionInterface interface {
 	Decrypt(rsaPrivateKey *rsa.PrivateKey, ciphertext, encryptedAESKey, nonce []byte) ([]byte, error)
 	CreateDigitalSignature(ed25519PrivateKey ed25519.PrivateKey, ciphertext, aesKey, nonce []byte) (string, error)
 	VerifyDigitalSignature(base64EncryptedPayload string, signatureBase64 string, senderPublicKey ed25519.PublicKey) error
+	Sign(ed25519PrivateKey ed25519.PrivateKey, data []byte) (string, error)
+	Verify(data []byte, signatureBase64 string, senderPublicKey ed25519.PublicKey) error
 	DecodeBase64Encrypted(base64EncryptedPayload string) ([]byte, []byte, []byte, error)
 	CreateBase64Encrypted(ciphertext, encryptedAESKey, nonce []byte) (string, error)
 }
@@ -0,0 +1,32 @@
+package port
+
+import "time"
+
+// IdempotencyRecord is what the bridge remembers of a request sent with an
+// Idempotency-Key: a fingerprint of the request, and once it completed the
+// response that was sent back.
+type IdempotencyRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	// false while the first request with the key is in flight
+	Completed  bool              `json:"completed"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       []byte            `json:"body,omitempty"`
+}
+
+// IdempotencyStore keeps idempotency records per application. Expired records
+// are treated as absent.
+type IdempotencyStore interface {
+	// Reserve stores record under the key unless a live record is already
+	// there, in which case that one is returned with true.
+	Reserve(applicationSlug, key string, record IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// Complete replaces the record of a reserved key.
+	Complete(applicationSlug, key string, record IdempotencyRecord) error
+	// Release forgets a reserved key, so that the request can be sent again.
+	Release(applicationSlug, key string) error
+	// Purge deletes the records that expired before now and returns how many.
+	Purge(now time.Time) (int, error)
+}
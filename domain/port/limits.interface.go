@@ -0,0 +1,54 @@
+package port
+
+import "time"
+
+// Scopes a limit applies to.
+const (
+	LimitScopeApplication = "application"
+	LimitScopeBank        = "bank"
+	LimitScopeRoute       = "route"
+)
+
+// Limit caps the requests of one application, bank or route.
+type Limit struct {
+	// sustained requests per second, 0 for no rate limit
+	Rate float64 `json:"rate,omitempty"`
+	// requests admitted at once above the rate, defaults to the rate rounded
+	// up
+	Burst int `json:"burst,omitempty"`
+	// requests in flight at the same time, 0 for no cap
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+type LimitsConfig interface {
+	// Limit returns the limit of an application slug, bank slug or route
+	// path. The boolean is false when neither the name nor the scope's
+	// default has one.
+	Limit(scope, name string) (Limit, bool)
+}
+
+// LimitKey names what a request is counted against.
+type LimitKey struct {
+	Scope string
+	Name  string
+}
+
+// Reasons a request is refused.
+const (
+	LimitReasonRate        = "rate"
+	LimitReasonConcurrency = "concurrency"
+)
+
+type LimitRefusal struct {
+	Key    LimitKey
+	Reason string
+	// how long until a retry may be admitted
+	RetryAfter time.Duration
+}
+
+type RateLimiter interface {
+	// Acquire admits a request counted against every key, and returns the
+	// function to call once it is done. A request refused by one of the keys
+	// counts against none of them.
+	Acquire(keys []LimitKey) (release func(), refusal *LimitRefusal)
+}
@@ -7,5 +7,7 @@ type Logger interface {
 	Error(msg string, fields ...interface{})
 	Panic(msg string, fields ...interface{})
 	Fatal(msg string, fields ...interface{})
+	// With returns a logger adding the fields to every line.
+	With(fields ...interface{}) Logger
 	Sync() error
 }
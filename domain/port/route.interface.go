@@ -0,0 +1,35 @@
+package port
+
+import (
+	"slices"
+	"time"
+)
+
+// Route maps a resource route served by the bridge to the Rapid Links path it
+// is forwarded to.
+type Route struct {
+	Method string
+	// inbound path below the resource prefix, e.g. /balance
+	Path string
+	// upstream path with the path parameters of the request filled in
+	UpstreamPath string
+	Timeout      time.Duration
+	// banks the route may be forwarded to, empty for all of them
+	AllowedBanks []string
+	// whether sending the request twice is harmless, which allows retrying
+	// it after a failure that may have reached the bank
+	Idempotent bool
+}
+
+func (r Route) AllowsBank(bankSlug string) bool {
+	return len(r.AllowedBanks) == 0 || slices.Contains(r.AllowedBanks, bankSlug)
+}
+
+type RouteTable interface {
+	// Match returns the first route for the method and the path below the
+	// resource prefix.
+	Match(method, path string) (Route, bool)
+	// Methods lists the methods routed for a path, so that an unknown method
+	// can be told apart from an unknown path.
+	Methods(path string) []string
+}
@@ -0,0 +1,49 @@
+package port
+
+import (
+	"net/http"
+	"time"
+)
+
+type UpstreamPool interface {
+	// Order returns the urls with healthy endpoints first, keeping the
+	// configured order within each group.
+	Order(urls []string) []string
+	MarkSuccess(url string)
+	MarkFailure(url string, err error)
+	IsHealthy(url string) bool
+}
+
+// CircuitBreaker fails requests to a bank fast after repeated upstream
+// failures, instead of letting every caller wait for the timeout.
+type CircuitBreaker interface {
+	// Allow reports whether a request may be sent, and otherwise how long
+	// until the bank is tried again
+	Allow(bankSlug string) (time.Duration, bool)
+	RecordSuccess(bankSlug string)
+	RecordFailure(bankSlug string)
+	State(bankSlug string) string
+}
+
+// UpstreamTLS is the TLS configuration of the connection to a bank's Rapid
+// Links, kept under "tls" in the bank config. File paths are read when the
+// connection is set up.
+type UpstreamTLS struct {
+	// PEM bundle replacing the system roots
+	CAFile string `json:"ca_file,omitempty"`
+	// client certificate presented to the bank's gateway
+	CertFile   string `json:"cert_file,omitempty"`
+	KeyFile    string `json:"key_file,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	// "1.2" or "1.3", defaults to 1.2
+	MinVersion string `json:"min_version,omitempty"`
+	// sha256/<base64> of a certificate's SubjectPublicKeyInfo; when set, the
+	// verified chain must contain one of them
+	SPKIPins []string `json:"spki_pins,omitempty"`
+}
+
+// UpstreamClients hands out the HTTP client to reach a bank with. nil means
+// the default TLS settings.
+type UpstreamClients interface {
+	Client(upstreamTLS *UpstreamTLS) (*http.Client, error)
+}
@@ -26,6 +26,15 @@ func (s *Security) VerifyDigitalSignature(base64EncryptedPayload string, signatu
 	return s.Cipher.VerifyDigitalSignature(base64EncryptedPayload, signatureBase64, senderPublicKey)
 }
 
+// Sign signs arbitrary data, such as envelope metadata, with the Ed25519 key.
+func (s *Security) Sign(ed25519PrivateKey ed25519.PrivateKey, data []byte) (string, error) {
+	return s.Cipher.Sign(ed25519PrivateKey, data)
+}
+
+func (s *Security) Verify(data []byte, signatureBase64 string, senderPublicKey ed25519.PublicKey) error {
+	return s.Cipher.Verify(data, signatureBase64, senderPublicKey)
+}
+
 func (s *Security) DecodeBase64Encrypted(base64EncryptedPayload string) ([]byte, []byte, []byte, error) {
 	return s.Cipher.DecodeBase64Encrypted(base64EncryptedPayload)
 }
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/migration"
+	"slices"
 	"time"
 
 	"github.com/spf13/viper"
@@ -18,17 +20,25 @@ type CLIConfig struct {
 	BankDetails        BankDetails                `json:"bank"`
 
 	RegisteredApplications []string `mapstructure:"registered_applications"`
+	DisabledApplications   []string `mapstructure:"disabled_applications"`
 	RegisteredBanks        []string `mapstructure:"registered_banks"`
 }
 
 type FlatCLIConfig struct {
 	RapidLinksURL          string   `json:"rapid_links_url"`
+	ForwardHeaders         []string `json:"forward_headers,omitempty"`
 	RegisteredApplications []string `json:"registered_applications"`
+	DisabledApplications   []string `json:"disabled_applications"`
 	RegisteredBanks        []string `json:"registered_banks"`
 }
 
 type FileConfigAdapter struct {
 	CLIConfig
+	DataDir string
+}
+
+func (f *FileConfigAdapter) GetDataDir() string {
+	return f.DataDir
 }
 
 func (f *FileConfigAdapter) GetRegisteredBanks() []string {
@@ -39,6 +49,67 @@ func (f *FileConfigAdapter) GetRegisteredApplications() []string {
 	return f.CLIConfig.RegisteredApplications
 }
 
+func (f *FileConfigAdapter) GetDisabledApplications() []string {
+	return f.CLIConfig.DisabledApplications
+}
+
+func (f *FileConfigAdapter) DisableApplication(applicationSlug string) {
+	if !slices.Contains(f.CLIConfig.DisabledApplications, applicationSlug) {
+		f.CLIConfig.DisabledApplications = append(f.CLIConfig.DisabledApplications, applicationSlug)
+	}
+}
+
+func (f *FileConfigAdapter) EnableApplication(applicationSlug string) {
+	f.CLIConfig.DisabledApplications = slices.DeleteFunc(f.CLIConfig.DisabledApplications, func(slug string) bool {
+		return slug == applicationSlug
+	})
+}
+
+func (f *FileConfigAdapter) RemoveRegisteredApplication(applicationSlug string) {
+	f.CLIConfig.RegisteredApplications = slices.DeleteFunc(f.CLIConfig.RegisteredApplications, func(slug string) bool {
+		return slug == applicationSlug
+	})
+	f.EnableApplication(applicationSlug)
+}
+
+func (f *FileConfigAdapter) RemoveRegisteredBank(bankSlug string) {
+	f.CLIConfig.RegisteredBanks = slices.DeleteFunc(f.CLIConfig.RegisteredBanks, func(slug string) bool {
+		return slug == bankSlug
+	})
+}
+
+func (f *FileConfigAdapter) LoadApplicationDetails(applicationSlug string) (*port.CLIApplicationDetails, error) {
+	configPath := filepath.Join(f.DataDir, constants.Application, applicationSlug, applicationSlug+".json")
+
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading application config file: %w", err)
+	}
+
+	var applicationDetails port.CLIApplicationDetails
+	if err := json.Unmarshal(configData, &applicationDetails); err != nil {
+		return nil, fmt.Errorf("unable to decode application config %s: %w", configPath, err)
+	}
+
+	return &applicationDetails, nil
+}
+
+func (f *FileConfigAdapter) LoadBankDetails(bankSlug string) (*port.CLIBankDetails, error) {
+	bankDetails, err := LoadBankSpecificConfig(f.DataDir, bankSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	return &port.CLIBankDetails{
+		RSAPublicKeyPath:     bankDetails.RSAPublicKeyPath,
+		Ed25519PublicKeyPath: bankDetails.Ed25519PublicKeyPath,
+		RapidUrls:            bankDetails.RapidUrls,
+		TLS:                  bankDetails.TLS,
+		ForwardHeaders:       bankDetails.ForwardHeaders,
+		Slug:                 bankSlug,
+	}, nil
+}
+
 func (f *FileConfigAdapter) GetApplicationDetails(applicationSlug string) *port.CLIApplicationDetails {
 	return &f.CLIConfig.ApplicationDetails
 }
@@ -80,10 +151,22 @@ func (f *FileConfigAdapter) AddBankKeysPaths(rsaPublicKeyPath string, ed25519Pub
 	f.CLIConfig.BankDetails.Ed25519PublicKeyPath = ed25519PublicKeyPath
 }
 
+func (f *FileConfigAdapter) AddBankRapidUrls(rapidUrls []string) {
+	f.CLIConfig.BankDetails.RapidUrls = rapidUrls
+}
+
+func (f *FileConfigAdapter) AddBankTLS(upstreamTLS *port.UpstreamTLS) {
+	f.CLIConfig.BankDetails.TLS = upstreamTLS
+}
+
+func (f *FileConfigAdapter) AddBankForwardHeaders(headers []string) {
+	f.CLIConfig.BankDetails.ForwardHeaders = headers
+}
+
 func (f *FileConfigAdapter) SaveApplicationConfigToFile() error {
 	applicationSlug := f.CLIConfig.ApplicationDetails.Slug
 
-	folderPath := filepath.Join(constants.RapidBridgeData, constants.Application, applicationSlug)
+	folderPath := filepath.Join(f.DataDir, constants.Application, applicationSlug)
 	filePath := filepath.Join(folderPath, applicationSlug+".json")
 
 	// create folder
@@ -118,7 +201,7 @@ func (f *FileConfigAdapter) SaveBankConfigToFile() error {
 
 	bankSlug := f.CLIConfig.BankDetails.Slug
 
-	folderPath := filepath.Join(constants.RapidBridgeData, constants.Bank, bankSlug)
+	folderPath := filepath.Join(f.DataDir, constants.Bank, bankSlug)
 	filePath := filepath.Join(folderPath, bankSlug+".json")
 
 	// create folder
@@ -154,42 +237,85 @@ func (f *FileConfigAdapter) SaveConfigToFile() error {
 	var flatCliConfig FlatCLIConfig
 
 	flatCliConfig.RapidLinksURL = f.CLIConfig.RapidLinks.Url
+	flatCliConfig.ForwardHeaders = f.CLIConfig.RapidLinks.ForwardHeaders
 	flatCliConfig.RegisteredApplications = f.CLIConfig.RegisteredApplications
+	flatCliConfig.DisabledApplications = f.CLIConfig.DisabledApplications
 	flatCliConfig.RegisteredBanks = f.CLIConfig.RegisteredBanks
 
 	data, err := json.MarshalIndent(flatCliConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
-	if err := os.WriteFile(constants.RapidBridgeData+"/core.json", data, 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(f.DataDir, constants.CoreConfigFile), data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 	return nil
 }
 
-func LoadCLIConfig() (port.CLIConfig, error) {
+// BootstrapDataDir creates the data directory layout, an empty core.json and
+// the schema version file when they do not exist yet. Existing files are left
+// untouched. Only init and serve bootstrap, other commands need an
+// initialized data directory.
+func BootstrapDataDir(dataDir string) error {
+	for _, dir := range []string{dataDir, filepath.Join(dataDir, constants.Application), filepath.Join(dataDir, constants.Bank)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	coreConfigPath := filepath.Join(dataDir, constants.CoreConfigFile)
+	if _, err := os.Stat(coreConfigPath); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat %s: %w", coreConfigPath, err)
+	}
+
+	data, err := json.MarshalIndent(FlatCLIConfig{
+		RegisteredApplications: []string{},
+		DisabledApplications:   []string{},
+		RegisteredBanks:        []string{},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(coreConfigPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return migration.WriteSchemaVersion(dataDir, constants.DataSchemaVersion)
+}
+
+func LoadCLIConfig(dataDir string) (port.CLIConfig, error) {
 
 	var cliConfig CLIConfig
 
+	if _, err := os.Stat(filepath.Join(dataDir, constants.CoreConfigFile)); os.IsNotExist(err) {
+		return nil, fmt.Errorf("data directory %s not initialized, run rapid-bridge init app or init bank first", dataDir)
+	}
+
+	if err := migration.CheckSchemaVersion(dataDir); err != nil {
+		return nil, err
+	}
+
 	v := viper.New()
 
 	v.SetConfigName("core")
 	v.SetConfigType("json")
-	v.AddConfigPath(constants.RapidBridgeData)
+	v.AddConfigPath(dataDir)
 
 	if err := v.ReadInConfig(); err != nil {
-		panic(fmt.Errorf("fatal error reading config file: %w", err))
+		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
 
 	if err := v.Unmarshal(&cliConfig); err != nil {
-		panic(fmt.Errorf("unable to decode into struct: %w", err))
+		return nil, fmt.Errorf("unable to decode into struct: %w", err)
 	}
 
-	return &FileConfigAdapter{CLIConfig: cliConfig}, nil
+	return &FileConfigAdapter{CLIConfig: cliConfig, DataDir: dataDir}, nil
 }
 
-func LoadApplicationSpecificConfig(applicationSlug string) port.ApplicationDetails {
-	configPath := filepath.Join(constants.RapidBridgeData, constants.Application, applicationSlug, applicationSlug+".json")
+func LoadApplicationSpecificConfig(dataDir, applicationSlug string) port.ApplicationDetails {
+	configPath := filepath.Join(dataDir, constants.Application, applicationSlug, applicationSlug+".json")
 
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
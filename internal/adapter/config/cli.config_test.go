@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCLIConfigNeedsInitializedDataDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		bootstrap bool
+		wantErr   bool
+	}{
+		{"not initialized", false, true},
+		{"initialized", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := filepath.Join(t.TempDir(), "data")
+			if tt.bootstrap {
+				if err := BootstrapDataDir(dataDir); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := LoadCLIConfig(dataDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCLIConfig = %v, want error %v", err, tt.wantErr)
+			}
+			if _, statErr := os.Stat(dataDir); !tt.bootstrap && !os.IsNotExist(statErr) {
+				t.Fatalf("LoadCLIConfig created %s", dataDir)
+			}
+		})
+	}
+}
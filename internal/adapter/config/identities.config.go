@@ -0,0 +1,133 @@
+package config
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/util"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ClientIdentityConfig is one entry of client_identities.json.
+type ClientIdentityConfig struct {
+	// a SPIFFE ID such as spiffe://example.org/erp, or a certificate subject
+	// such as CN=erp,O=Example
+	Identity    string `json:"identity"`
+	Application string `json:"application"`
+}
+
+type ClientIdentitiesFile struct {
+	Identities []ClientIdentityConfig `json:"identities"`
+}
+
+// ClientIdentityAdapter maps client certificates to applications with
+// client_identities.json, re-reading it when it changes.
+// Certificates without an entry are rejected, unless commonNameFallback maps
+// them to the application named by their subject common name. An invalid
+// file is logged and the last good mapping stays in use.
+type ClientIdentityAdapter struct {
+	DataDir string
+	logger  port.Logger
+
+	commonNameFallback bool
+
+	identities *util.Reloader[map[string]string]
+}
+
+// CertificateIdentity is the SPIFFE ID of a certificate when it has one,
+// otherwise its subject.
+func CertificateIdentity(certificate *x509.Certificate) string {
+	if spiffeID, ok := spiffeIdentity(certificate); ok {
+		return spiffeID
+	}
+	return certificate.Subject.String()
+}
+
+func spiffeIdentity(certificate *x509.Certificate) (string, bool) {
+	for _, uri := range certificate.URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String(), true
+		}
+	}
+	return "", false
+}
+
+func (c *ClientIdentityAdapter) ApplicationForCertificate(certificate *x509.Certificate) (string, bool) {
+	if applicationSlug, ok := c.current()[CertificateIdentity(certificate)]; ok {
+		return applicationSlug, true
+	}
+
+	// a SPIFFE certificate is only ever known by its SPIFFE ID
+	if _, ok := spiffeIdentity(certificate); ok || !c.commonNameFallback {
+		return "", false
+	}
+
+	commonName := certificate.Subject.CommonName
+	return commonName, commonName != ""
+}
+
+func (c *ClientIdentityAdapter) current() map[string]string {
+	identities, reloaded, err := c.identities.Current()
+	if err != nil {
+		c.logger.Error("Invalid client identities, keeping the previous ones", zap.String("error", err.Error()))
+	} else if reloaded {
+		c.logger.Info("Client identities reloaded", zap.Int("identities", len(identities)))
+	}
+	return identities
+}
+
+// LoadClientIdentities reads client_identities.json, which may be missing.
+// Unlike a reload, an invalid file is an error here. With commonNameFallback,
+// certificates without an entry map to their subject common name.
+func LoadClientIdentities(dataDir string, commonNameFallback bool, logger port.Logger) (*ClientIdentityAdapter, error) {
+	identities, err := util.NewReloader(func() (map[string]string, error) {
+		return loadClientIdentities(dataDir)
+	}, filepath.Join(dataDir, constants.ClientIdentitiesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientIdentityAdapter{
+		DataDir:    dataDir,
+		logger:     logger,
+		identities: identities,
+
+		commonNameFallback: commonNameFallback,
+	}, nil
+}
+
+func loadClientIdentities(dataDir string) (map[string]string, error) {
+	identitiesPath := filepath.Join(dataDir, constants.ClientIdentitiesFile)
+
+	data, err := os.ReadFile(identitiesPath)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading client identities: %w", err)
+	}
+
+	identitiesFile := ClientIdentitiesFile{}
+	if err := json.Unmarshal(data, &identitiesFile); err != nil {
+		return nil, fmt.Errorf("unable to decode client identities %s: %w", identitiesPath, err)
+	}
+
+	identities := map[string]string{}
+	for i, entry := range identitiesFile.Identities {
+		if strings.TrimSpace(entry.Identity) == "" || strings.TrimSpace(entry.Application) == "" {
+			return nil, fmt.Errorf("client identity %d: identity and application are required", i+1)
+		}
+		if _, ok := identities[entry.Identity]; ok {
+			return nil, fmt.Errorf("client identity %d: %s is mapped twice", i+1, entry.Identity)
+		}
+		identities[entry.Identity] = entry.Application
+	}
+
+	return identities, nil
+}
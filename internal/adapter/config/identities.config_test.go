@@ -0,0 +1,68 @@
+package config
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/adapter/logger"
+	"testing"
+)
+
+func TestApplicationForCertificate(t *testing.T) {
+	dataDir := t.TempDir()
+	identities := `{"identities": [
+		{"identity": "spiffe://example.org/erp", "application": "erp-app"},
+		{"identity": "CN=billing,O=Example", "application": "billing-app"}
+	]}`
+	if err := os.WriteFile(filepath.Join(dataDir, constants.ClientIdentitiesFile), []byte(identities), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spiffeCertificate := func(id string, commonName string) *x509.Certificate {
+		uri, err := url.Parse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &x509.Certificate{URIs: []*url.URL{uri}, Subject: pkix.Name{CommonName: commonName}}
+	}
+	subjectCertificate := func(commonName string, organization ...string) *x509.Certificate {
+		return &x509.Certificate{Subject: pkix.Name{CommonName: commonName, Organization: organization}}
+	}
+
+	tests := []struct {
+		name            string
+		certificate     *x509.Certificate
+		fallback        bool
+		wantApplication string
+		wantOK          bool
+	}{
+		{"mapped spiffe id", spiffeCertificate("spiffe://example.org/erp", ""), false, "erp-app", true},
+		{"mapped subject", subjectCertificate("billing", "Example"), false, "billing-app", true},
+		{"unmapped common name", subjectCertificate("victim-app"), false, "", false},
+		{"unmapped common name with fallback", subjectCertificate("victim-app"), true, "victim-app", true},
+		{"unmapped spiffe id", spiffeCertificate("spiffe://example.org/other", "victim-app"), false, "", false},
+		{"unmapped spiffe id with fallback", spiffeCertificate("spiffe://example.org/other", "victim-app"), true, "", false},
+		{"no common name with fallback", subjectCertificate("", "Example"), true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := LoadClientIdentities(dataDir, tt.fallback, zapLogger)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			application, ok := adapter.ApplicationForCertificate(tt.certificate)
+			if application != tt.wantApplication || ok != tt.wantOK {
+				t.Fatalf("ApplicationForCertificate = %q, %v, want %q, %v", application, ok, tt.wantApplication, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+// LimitsDefault is the key of the limit applied to every application, bank or
+// route without its own entry.
+const LimitsDefault = "*"
+
+// LimitsFile is the content of limits.json. Routes are keyed by their path as
+// in routes.json, e.g. /payment/initiate.
+type LimitsFile struct {
+	Applications map[string]port.Limit `json:"applications,omitempty"`
+	Banks        map[string]port.Limit `json:"banks,omitempty"`
+	Routes       map[string]port.Limit `json:"routes,omitempty"`
+}
+
+// LimitsAdapter serves the limits from limits.json, re-reading it when it
+// changes. Without the file nothing is limited. An invalid file is logged and
+// the last good limits stay in use.
+type LimitsAdapter struct {
+	DataDir string
+	logger  port.Logger
+
+	limits *util.Reloader[LimitsFile]
+}
+
+func (l *LimitsAdapter) Limit(scope, name string) (port.Limit, bool) {
+	limits := l.current()
+
+	var byName map[string]port.Limit
+	switch scope {
+	case port.LimitScopeApplication:
+		byName = limits.Applications
+	case port.LimitScopeBank:
+		byName = limits.Banks
+	case port.LimitScopeRoute:
+		byName = limits.Routes
+	}
+
+	if limit, ok := byName[name]; ok {
+		return limit, true
+	}
+	limit, ok := byName[LimitsDefault]
+	return limit, ok
+}
+
+func (l *LimitsAdapter) current() LimitsFile {
+	limits, reloaded, err := l.limits.Current()
+	if err != nil {
+		l.logger.Error("Invalid limits, keeping the previous ones", zap.String("error", err.Error()))
+	} else if reloaded {
+		l.logger.Info("Limits reloaded", zap.Int("applications", len(limits.Applications)), zap.Int("banks", len(limits.Banks)), zap.Int("routes", len(limits.Routes)))
+	}
+	return limits
+}
+
+// LoadLimits reads limits.json. Unlike a reload, an invalid file is an error
+// here.
+func LoadLimits(dataDir string, logger port.Logger) (*LimitsAdapter, error) {
+	limits, err := util.NewReloader(func() (LimitsFile, error) {
+		return ReadLimits(dataDir)
+	}, filepath.Join(dataDir, constants.LimitsConfigFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return &LimitsAdapter{
+		DataDir: dataDir,
+		logger:  logger,
+		limits:  limits,
+	}, nil
+}
+
+// ReadLimits returns the validated limits of limits.json, none when the file
+// does not exist. Bursts left out are filled in.
+func ReadLimits(dataDir string) (LimitsFile, error) {
+	limitsPath := filepath.Join(dataDir, constants.LimitsConfigFile)
+
+	data, err := os.ReadFile(limitsPath)
+	if os.IsNotExist(err) {
+		return LimitsFile{}, nil
+	}
+	if err != nil {
+		return LimitsFile{}, fmt.Errorf("error reading limits: %w", err)
+	}
+
+	limits := LimitsFile{}
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return LimitsFile{}, fmt.Errorf("unable to decode limits %s: %w", limitsPath, err)
+	}
+
+	for scope, byName := range map[string]map[string]port.Limit{
+		port.LimitScopeApplication: limits.Applications,
+		port.LimitScopeBank:        limits.Banks,
+		port.LimitScopeRoute:       limits.Routes,
+	} {
+		for name, limit := range byName {
+			if limit.Rate < 0 || limit.Burst < 0 || limit.Concurrency < 0 {
+				return LimitsFile{}, fmt.Errorf("limit of %s %s must not be negative", scope, name)
+			}
+			if limit.Rate > 0 && limit.Burst == 0 {
+				limit.Burst = int(math.Ceil(limit.Rate))
+				byName[name] = limit
+			}
+		}
+	}
+
+	return limits, nil
+}
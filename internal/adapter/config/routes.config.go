@@ -0,0 +1,296 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/util"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// RouteConfig is one entry of routes.json.
+type RouteConfig struct {
+	Method string `json:"method"`
+	// inbound path below /api/v1/resource, segments like {id} match any value
+	Path string `json:"path"`
+	// defaults to the inbound path under /api/v1/resource, and may use the
+	// inbound path parameters
+	UpstreamPath string `json:"upstream_path,omitempty"`
+	// Go duration, e.g. "10s"
+	Timeout      string   `json:"timeout,omitempty"`
+	AllowedBanks []string `json:"allowed_banks,omitempty"`
+	// defaults to true for GET, PUT and DELETE
+	Idempotent *bool `json:"idempotent,omitempty"`
+}
+
+type RoutesFile struct {
+	Routes []RouteConfig `json:"routes"`
+}
+
+// DefaultRoutes are served when the data directory has no routes.json.
+var DefaultRoutes = []RouteConfig{
+	{Method: http.MethodPost, Path: "/balance", Idempotent: &idempotent},
+	{Method: http.MethodPost, Path: "/statement", Idempotent: &idempotent},
+	{Method: http.MethodPost, Path: "/payment/initiate"},
+	{Method: http.MethodPost, Path: "/payment/approve"},
+	{Method: http.MethodPost, Path: "/account/open"},
+}
+
+// balance and statement are read-only although they are POSTed
+var idempotent = true
+
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+type compiledRoute struct {
+	port.Route
+	segments         []string
+	upstreamSegments []string
+}
+
+// RouteTableAdapter serves the routes from routes.json, re-reading it when
+// it changes like ServerConfigAdapter does for core.json. An invalid file is
+// logged and the last good table stays in use.
+type RouteTableAdapter struct {
+	DataDir string
+	logger  port.Logger
+
+	routes *util.Reloader[[]compiledRoute]
+}
+
+func (r *RouteTableAdapter) Match(method, path string) (port.Route, bool) {
+	for _, route := range r.current() {
+		if route.Method != method {
+			continue
+		}
+		if params, ok := matchSegments(route.segments, splitPath(path)); ok {
+			matched := route.Route
+			matched.UpstreamPath = expandSegments(route.upstreamSegments, params)
+			return matched, true
+		}
+	}
+	return port.Route{}, false
+}
+
+func (r *RouteTableAdapter) Methods(path string) []string {
+	methods := []string{}
+	for _, route := range r.current() {
+		if _, ok := matchSegments(route.segments, splitPath(path)); ok && !slices.Contains(methods, route.Method) {
+			methods = append(methods, route.Method)
+		}
+	}
+	return methods
+}
+
+// Routes returns the table in use, with upstream paths as configured.
+func (r *RouteTableAdapter) Routes() []port.Route {
+	routes := []port.Route{}
+	for _, route := range r.current() {
+		routes = append(routes, route.Route)
+	}
+	return routes
+}
+
+func (r *RouteTableAdapter) current() []compiledRoute {
+	routes, reloaded, err := r.routes.Current()
+	if err != nil {
+		r.logger.Error("Invalid route table, keeping the previous one", zap.String("error", err.Error()))
+	} else if reloaded {
+		r.logger.Info("Route table reloaded", zap.Int("routes", len(routes)))
+	}
+	return routes
+}
+
+// LoadRouteTable reads routes.json, or the default routes when there is none.
+// Unlike a reload, an invalid file is an error here.
+func LoadRouteTable(dataDir string, logger port.Logger) (*RouteTableAdapter, error) {
+	routes, err := util.NewReloader(func() ([]compiledRoute, error) {
+		return loadRoutes(dataDir)
+	}, filepath.Join(dataDir, constants.RoutesConfigFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RouteTableAdapter{
+		DataDir: dataDir,
+		logger:  logger,
+		routes:  routes,
+	}, nil
+}
+
+// ReadRoutes returns the routes configured in routes.json, or DefaultRoutes
+// when the file does not exist.
+func ReadRoutes(dataDir string) ([]RouteConfig, error) {
+	routesPath := filepath.Join(dataDir, constants.RoutesConfigFile)
+
+	data, err := os.ReadFile(routesPath)
+	if os.IsNotExist(err) {
+		return DefaultRoutes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading route table: %w", err)
+	}
+
+	routesFile := RoutesFile{}
+	if err := json.Unmarshal(data, &routesFile); err != nil {
+		return nil, fmt.Errorf("unable to decode route table %s: %w", routesPath, err)
+	}
+
+	return routesFile.Routes, nil
+}
+
+func loadRoutes(dataDir string) ([]compiledRoute, error) {
+	routes, err := ReadRoutes(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return compileRoutes(routes)
+}
+
+// ValidateRoutes reports the first problem in a route table.
+func ValidateRoutes(routes []RouteConfig) error {
+	_, err := compileRoutes(routes)
+	return err
+}
+
+func compileRoutes(routes []RouteConfig) ([]compiledRoute, error) {
+	compiled := make([]compiledRoute, 0, len(routes))
+	seen := map[string]bool{}
+
+	for i, routeConfig := range routes {
+		route, err := compileRoute(routeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("route %d (%s %s): %w", i+1, routeConfig.Method, routeConfig.Path, err)
+		}
+
+		key := route.Method + " " + route.Path
+		if seen[key] {
+			return nil, fmt.Errorf("route %d: %s is defined twice", i+1, key)
+		}
+		seen[key] = true
+
+		compiled = append(compiled, route)
+	}
+
+	return compiled, nil
+}
+
+func compileRoute(routeConfig RouteConfig) (compiledRoute, error) {
+	method := strings.ToUpper(routeConfig.Method)
+	if !slices.Contains(routeMethods, method) {
+		return compiledRoute{}, fmt.Errorf("unsupported method %q, use one of %s", routeConfig.Method, strings.Join(routeMethods, ", "))
+	}
+
+	if !strings.HasPrefix(routeConfig.Path, "/") || len(splitPath(routeConfig.Path)) == 0 {
+		return compiledRoute{}, fmt.Errorf("path must start with / and not be empty")
+	}
+	segments := splitPath(routeConfig.Path)
+
+	upstreamPath := routeConfig.UpstreamPath
+	if upstreamPath == "" {
+		upstreamPath = strings.TrimSuffix(constants.ResourcePathPrefix, "/") + "/" + strings.Join(segments, "/")
+	}
+	if !strings.HasPrefix(upstreamPath, "/") {
+		return compiledRoute{}, fmt.Errorf("upstream_path must start with /")
+	}
+	upstreamSegments := splitPath(upstreamPath)
+
+	params := map[string]bool{}
+	for _, segment := range segments {
+		if name, ok := paramName(segment); ok {
+			params[name] = true
+		}
+	}
+	for _, segment := range upstreamSegments {
+		if name, ok := paramName(segment); ok && !params[name] {
+			return compiledRoute{}, fmt.Errorf("upstream_path uses {%s} which is not in the path", name)
+		}
+	}
+
+	timeout := constants.DefaultRouteTimeout * time.Second
+	if routeConfig.Timeout != "" {
+		parsed, err := time.ParseDuration(routeConfig.Timeout)
+		if err != nil || parsed <= 0 {
+			return compiledRoute{}, fmt.Errorf("invalid timeout %q, expected a positive duration such as 10s", routeConfig.Timeout)
+		}
+		timeout = parsed
+	}
+
+	isIdempotent := method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+	if routeConfig.Idempotent != nil {
+		isIdempotent = *routeConfig.Idempotent
+	}
+
+	return compiledRoute{
+		Route: port.Route{
+			Method:       method,
+			Path:         "/" + strings.Join(segments, "/"),
+			UpstreamPath: "/" + strings.Join(upstreamSegments, "/"),
+			Timeout:      timeout,
+			AllowedBanks: routeConfig.AllowedBanks,
+			Idempotent:   isIdempotent,
+		},
+		segments:         segments,
+		upstreamSegments: upstreamSegments,
+	}, nil
+}
+
+func splitPath(path string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func paramName(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+func matchSegments(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range pattern {
+		if name, ok := paramName(segment); ok {
+			// a decoded %0A would otherwise reach the upstream path
+			if strings.ContainsFunc(segments[i], unicode.IsControl) {
+				return nil, false
+			}
+			params[name] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func expandSegments(segments []string, params map[string]string) string {
+	expanded := make([]string, len(segments))
+	for i, segment := range segments {
+		if name, ok := paramName(segment); ok {
+			expanded[i] = url.PathEscape(params[name])
+			continue
+		}
+		expanded[i] = segment
+	}
+	return "/" + strings.Join(expanded, "/")
+}
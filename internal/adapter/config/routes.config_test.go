@@ -0,0 +1,53 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/adapter/logger"
+	"testing"
+)
+
+func TestRouteTableMatch(t *testing.T) {
+	dataDir := t.TempDir()
+	routes := `{"routes": [
+		{"method": "GET", "path": "/transaction/{id}", "upstream_path": "/api/v2/transactions/{id}"}
+	]}`
+	if err := os.WriteFile(filepath.Join(dataDir, constants.RoutesConfigFile), []byte(routes), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := LoadRouteTable(dataDir, zapLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		path             string
+		wantOK           bool
+		wantUpstreamPath string
+	}{
+		{"parameter", "/transaction/42", true, "/api/v2/transactions/42"},
+		{"escaped parameter", "/transaction/a b", true, "/api/v2/transactions/a%20b"},
+		{"newline in parameter", "/transaction/a\nfalse", false, ""},
+		{"carriage return in parameter", "/transaction/a\r", false, ""},
+		{"unknown path", "/transactions/42", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, ok := table.Match(http.MethodGet, tt.path)
+			if ok != tt.wantOK {
+				t.Fatalf("Match ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && route.UpstreamPath != tt.wantUpstreamPath {
+				t.Fatalf("UpstreamPath = %q, want %q", route.UpstreamPath, tt.wantUpstreamPath)
+			}
+		})
+	}
+}
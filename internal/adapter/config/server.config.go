@@ -3,15 +3,24 @@ package config
 import (
 	"crypto/ed25519"
 	"crypto/rsa"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
 	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/migration"
+	"rapid-bridge/pkg/util"
+	"slices"
 
 	"github.com/spf13/viper"
 )
 
 type RapidLinks struct {
 	Url string `mapstructure:"rapid_links_url"`
+	// application headers forwarded to every bank
+	ForwardHeaders []string `mapstructure:"forward_headers"`
 }
 
 type ApplicationDetails struct {
@@ -42,6 +51,14 @@ type BankDetails struct {
 	RSAPublicKey     *rsa.PublicKey    `json:"rsa_public_key,omitempty"`
 	Ed25519PublicKey ed25519.PublicKey `json:"ed25519_public_key,omitempty"`
 
+	// upstream rapid links base urls, tried in order
+	RapidUrls []string `json:"rapid_urls"`
+
+	TLS *port.UpstreamTLS `json:"tls,omitempty"`
+
+	// application headers forwarded to this bank, besides the global ones
+	ForwardHeaders []string `json:"forward_headers,omitempty"`
+
 	Slug string `json:"slug"`
 }
 
@@ -49,34 +66,174 @@ type ServerConfig struct {
 	RapidLinks         RapidLinks
 	ApplicationDetails ApplicationDetails
 	BankDetails        BankDetails
+
+	RegisteredApplications []string
+	DisabledApplications   []string
+
+	// registered banks keyed by slug
+	Banks map[string]BankDetails
 }
 
+// ServerConfigAdapter re-reads core.json whenever it or the config file of
+// one of its banks changes, so that registry changes made with the CLI apply
+// to a running server without a restart. A file that cannot be read, for example while the CLI is
+// rewriting it, leaves the last good config in use.
 type ServerConfigAdapter struct {
-	ServerConfig
+	DataDir string
+
+	config *util.Reloader[ServerConfig]
+}
+
+func (s *ServerConfigAdapter) GetDataDir() string {
+	return s.DataDir
 }
 
 func (s *ServerConfigAdapter) GetRapidLinksUrl() string {
-	return s.ServerConfig.RapidLinks.Url
+	return s.current().RapidLinks.Url
+}
+
+// GetBankRapidUrls returns the upstream base urls of a registered bank. Banks
+// without their own urls fall back to the global rapid_links_url. The boolean
+// is false when no bank is registered under the slug.
+func (s *ServerConfigAdapter) GetBankRapidUrls(bankSlug string) ([]string, bool) {
+	cfg := s.current()
+
+	bankDetails, ok := cfg.Banks[bankSlug]
+	if !ok {
+		return nil, false
+	}
+
+	if len(bankDetails.RapidUrls) > 0 {
+		return bankDetails.RapidUrls, true
+	}
+
+	if cfg.RapidLinks.Url != "" {
+		return []string{cfg.RapidLinks.Url}, true
+	}
+
+	return []string{}, true
+}
+
+// GetBankTLS returns the bank's upstream TLS settings, nil for the defaults.
+func (s *ServerConfigAdapter) GetBankTLS(bankSlug string) *port.UpstreamTLS {
+	return s.current().Banks[bankSlug].TLS
+}
+
+func (s *ServerConfigAdapter) GetBankForwardHeaders(bankSlug string) []string {
+	cfg := s.current()
+	return slices.Concat(cfg.RapidLinks.ForwardHeaders, cfg.Banks[bankSlug].ForwardHeaders)
+}
+
+func (s *ServerConfigAdapter) GetRegisteredBanks() []string {
+	banks := make([]string, 0, len(s.current().Banks))
+	for bankSlug := range s.current().Banks {
+		banks = append(banks, bankSlug)
+	}
+	slices.Sort(banks)
+	return banks
+}
+
+func (s *ServerConfigAdapter) GetRegisteredApplications() []string {
+	return slices.Clone(s.current().RegisteredApplications)
+}
+
+func (s *ServerConfigAdapter) IsApplicationRegistered(applicationSlug string) bool {
+	return slices.Contains(s.current().RegisteredApplications, applicationSlug)
+}
+
+func (s *ServerConfigAdapter) IsApplicationDisabled(applicationSlug string) bool {
+	return slices.Contains(s.current().DisabledApplications, applicationSlug)
+}
+
+func (s *ServerConfigAdapter) current() ServerConfig {
+	cfg, _, _ := s.config.Current()
+	return cfg
+}
+
+func LoadServerConfig(dataDir string) (port.ServerConfig, error) {
+
+	if err := migration.CheckSchemaVersion(dataDir); err != nil {
+		return nil, err
+	}
+
+	config, err := util.NewReloaderFunc(func() (ServerConfig, error) {
+		return readServerConfig(dataDir)
+	}, func(cfg ServerConfig) []string {
+		return serverConfigFiles(dataDir, cfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	serverConfig := &ServerConfigAdapter{DataDir: dataDir, config: config}
+
+	return serverConfig, nil
+}
+
+// serverConfigFiles are core.json and the config files of the banks it
+// registers, which all go into a ServerConfig.
+func serverConfigFiles(dataDir string, cfg ServerConfig) []string {
+	files := []string{filepath.Join(dataDir, constants.CoreConfigFile)}
+	for _, bankSlug := range slices.Sorted(maps.Keys(cfg.Banks)) {
+		files = append(files, bankConfigPath(dataDir, bankSlug))
+	}
+	return files
 }
 
-func LoadServerConfig() (port.ServerConfig, error) {
+func readServerConfig(dataDir string) (ServerConfig, error) {
 
 	v := viper.New()
 
 	v.SetConfigName("core")
 	v.SetConfigType("json")
-	v.AddConfigPath(constants.RapidBridgeData)
+	v.AddConfigPath(dataDir)
 
 	if err := v.ReadInConfig(); err != nil {
-		panic(fmt.Errorf("fatal error reading config file: %w", err))
+		return ServerConfig{}, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	banks := make(map[string]BankDetails)
+	for _, bankSlug := range v.GetStringSlice("registered_banks") {
+		bankDetails, err := LoadBankSpecificConfig(dataDir, bankSlug)
+		if err != nil {
+			return ServerConfig{}, err
+		}
+		banks[bankSlug] = bankDetails
 	}
 
 	cfg := ServerConfig{
 		RapidLinks: RapidLinks{
-			Url: v.GetString("rapid_links_url"),
+			Url:            v.GetString("rapid_links_url"),
+			ForwardHeaders: v.GetStringSlice("forward_headers"),
 		},
+		RegisteredApplications: v.GetStringSlice("registered_applications"),
+		DisabledApplications:   v.GetStringSlice("disabled_applications"),
+		Banks:                  banks,
 	}
-	serverConfig := &ServerConfigAdapter{ServerConfig: cfg}
 
-	return serverConfig, nil
+	return cfg, nil
+}
+
+func LoadBankSpecificConfig(dataDir, bankSlug string) (BankDetails, error) {
+	configPath := bankConfigPath(dataDir, bankSlug)
+
+	bankDetails := BankDetails{Slug: bankSlug}
+
+	configData, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return bankDetails, nil
+	}
+	if err != nil {
+		return bankDetails, fmt.Errorf("error reading bank config file: %w", err)
+	}
+
+	if err := json.Unmarshal(configData, &bankDetails); err != nil {
+		return bankDetails, fmt.Errorf("unable to decode bank config %s: %w", configPath, err)
+	}
+
+	return bankDetails, nil
+}
+
+func bankConfigPath(dataDir, bankSlug string) string {
+	return filepath.Join(dataDir, constants.Bank, bankSlug, bankSlug+".json")
 }
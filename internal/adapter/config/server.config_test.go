@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestServerConfigReloadsBankConfig(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := BootstrapDataDir(dataDir); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corePath := filepath.Join(dataDir, constants.CoreConfigFile)
+	bankPath := func(bankSlug string) string {
+		return filepath.Join(dataDir, constants.Bank, bankSlug, bankSlug+".json")
+	}
+
+	write(corePath, `{"registered_banks": ["bank-a"]}`)
+	write(bankPath("bank-a"), `{"rapid_urls": ["http://a1"]}`)
+	for _, path := range []string{corePath, bankPath("bank-a")} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serverConfig, err := LoadServerConfig(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		bankSlug string
+		wantUrls []string
+	}{
+		{"loaded", "", "", "bank-a", []string{"http://a1"}},
+		{"bank config rewritten", bankPath("bank-a"), `{"rapid_urls": ["http://a2", "http://a3"]}`, "bank-a", []string{"http://a2", "http://a3"}},
+		{"bank registered", corePath, `{"registered_banks": ["bank-a", "bank-b"]}`, "bank-b", nil},
+		{"config of a new bank written", bankPath("bank-b"), `{"rapid_urls": ["http://b1"]}`, "bank-b", []string{"http://b1"}},
+		{"invalid bank config keeps the last good one", bankPath("bank-b"), `{"rapid_urls": `, "bank-b", []string{"http://b1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.path != "" {
+				write(tt.path, tt.content)
+			}
+
+			rapidUrls, ok := serverConfig.GetBankRapidUrls(tt.bankSlug)
+			if !ok {
+				t.Fatalf("bank %s is not registered", tt.bankSlug)
+			}
+			if tt.wantUrls == nil {
+				tt.wantUrls = []string{}
+			}
+			if !slices.Equal(rapidUrls, tt.wantUrls) {
+				t.Fatalf("GetBankRapidUrls = %v, want %v", rapidUrls, tt.wantUrls)
+			}
+		})
+	}
+}
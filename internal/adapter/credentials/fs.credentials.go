@@ -0,0 +1,94 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/util"
+	"sync"
+)
+
+type cachedAPIKeys struct {
+	stamp   util.FileStamp
+	apiKeys []port.APIKey
+}
+
+// FSCredentialStore keeps the API keys of an application in
+// application/<slug>/api_keys.json. Reads are cached until the file
+// changes, so that keys issued or revoked with the CLI
+// apply to a running server immediately.
+type FSCredentialStore struct {
+	DataDir string
+
+	mu    sync.Mutex
+	cache map[string]cachedAPIKeys
+}
+
+func NewFSCredentialStore(dataDir string) *FSCredentialStore {
+	return &FSCredentialStore{
+		DataDir: dataDir,
+		cache:   make(map[string]cachedAPIKeys),
+	}
+}
+
+func (f *FSCredentialStore) LoadAPIKeys(applicationSlug string) ([]port.APIKey, error) {
+	path := f.path(applicationSlug)
+
+	stamp := util.StatFiles(path)
+
+	f.mu.Lock()
+	cached, ok := f.cache[applicationSlug]
+	f.mu.Unlock()
+	if ok && stamp.Unchanged(cached.stamp) {
+		return cached.apiKeys, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []port.APIKey{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading api keys: %w", err)
+	}
+
+	apiKeys := []port.APIKey{}
+	if err := json.Unmarshal(data, &apiKeys); err != nil {
+		return nil, fmt.Errorf("unable to decode api keys %s: %w", path, err)
+	}
+
+	f.mu.Lock()
+	f.cache[applicationSlug] = cachedAPIKeys{stamp: stamp, apiKeys: apiKeys}
+	f.mu.Unlock()
+
+	return apiKeys, nil
+}
+
+func (f *FSCredentialStore) SaveAPIKeys(applicationSlug string, apiKeys []port.APIKey) error {
+	data, err := json.MarshalIndent(apiKeys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal api keys: %w", err)
+	}
+
+	path := f.path(applicationSlug)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// written aside and renamed, so that the server never reads half a file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+
+	return nil
+}
+
+func (f *FSCredentialStore) path(applicationSlug string) string {
+	return filepath.Join(f.DataDir, constants.Application, applicationSlug, constants.APIKeysFile)
+}
@@ -6,8 +6,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
 	"strings"
+	"time"
 )
 
 type HttpClient struct {
@@ -139,6 +141,15 @@ func (hc HttpClient) GET(baseUrl string, headers map[string]string, queryParam m
 func NewHttpClient(logger port.Logger) HttpClient {
 	return HttpClient{
 		logger: logger,
-		client: &http.Client{},
+		client: &http.Client{Timeout: constants.UpstreamRequestTimeout * time.Second},
+	}
+}
+
+// NewHttpClientWithClient sends through client, e.g. one configured with a
+// bank's upstream TLS settings, with the same overall timeout.
+func NewHttpClientWithClient(logger port.Logger, client *http.Client) HttpClient {
+	return HttpClient{
+		logger: logger,
+		client: &http.Client{Transport: client.Transport, Timeout: constants.UpstreamRequestTimeout * time.Second},
 	}
 }
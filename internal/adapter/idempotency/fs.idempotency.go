@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FSIdempotencyStore keeps one file per key in
+// idempotency/<application slug>/<sha256 of the key>.json. A key is reserved
+// by creating its file exclusively, so that bridges sharing a data directory
+// never both forward a request with the same key.
+type FSIdempotencyStore struct {
+	DataDir string
+
+	mu sync.Mutex
+}
+
+func NewFSIdempotencyStore(dataDir string) *FSIdempotencyStore {
+	return &FSIdempotencyStore{DataDir: dataDir}
+}
+
+func (f *FSIdempotencyStore) Reserve(applicationSlug, key string, record port.IdempotencyRecord) (port.IdempotencyRecord, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.path(applicationSlug, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return port.IdempotencyRecord{}, false, fmt.Errorf("error creating idempotency directory: %w", err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return port.IdempotencyRecord{}, false, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	// a second attempt follows the removal of an expired or unreadable record
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = file.Write(data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return port.IdempotencyRecord{}, false, fmt.Errorf("error writing idempotency record: %w", err)
+			}
+			return record, false, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return port.IdempotencyRecord{}, false, fmt.Errorf("error creating idempotency record: %w", err)
+		}
+
+		existing, err := readRecord(path)
+		if err == nil && time.Now().Before(existing.ExpiresAt) {
+			return existing, true, nil
+		}
+		// another bridge may be between creating the file and writing it
+		if info, statErr := os.Stat(path); err != nil && statErr == nil && time.Since(info.ModTime()) < constants.IdempotencyPendingTimeout*time.Minute {
+			return port.IdempotencyRecord{Fingerprint: record.Fingerprint, CreatedAt: info.ModTime()}, true, nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return port.IdempotencyRecord{}, false, fmt.Errorf("error removing idempotency record: %w", err)
+		}
+	}
+
+	return port.IdempotencyRecord{}, false, fmt.Errorf("idempotency key %s could not be reserved", key)
+}
+
+func (f *FSIdempotencyStore) Complete(applicationSlug, key string, record port.IdempotencyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	path := f.path(applicationSlug, key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing idempotency record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (f *FSIdempotencyStore) Release(applicationSlug, key string) error {
+	if err := os.Remove(f.path(applicationSlug, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error removing idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (f *FSIdempotencyStore) Purge(now time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	purged := 0
+	err := filepath.WalkDir(filepath.Join(f.DataDir, constants.IdempotencyDir), func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		record, err := readRecord(path)
+		if err == nil && now.Before(record.ExpiresAt) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		purged++
+		return nil
+	})
+	if err != nil {
+		return purged, fmt.Errorf("error purging idempotency records: %w", err)
+	}
+	return purged, nil
+}
+
+// StartPurging deletes expired records every interval until the returned
+// function is called.
+func (f *FSIdempotencyStore) StartPurging(interval time.Duration, logger port.Logger) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if purged, err := f.Purge(time.Now()); err != nil {
+					logger.Error("Failed to purge idempotency records", zap.String("error", err.Error()))
+				} else if purged > 0 {
+					logger.Info("Purged expired idempotency records", zap.Int("count", purged))
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// path hashes the key, which is chosen by the caller and may hold anything.
+func (f *FSIdempotencyStore) path(applicationSlug, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.DataDir, constants.IdempotencyDir, applicationSlug, hex.EncodeToString(sum[:])+".json")
+}
+
+func readRecord(path string) (port.IdempotencyRecord, error) {
+	var record port.IdempotencyRecord
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return record, err
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("unable to decode idempotency record %s: %w", path, err)
+	}
+	return record, nil
+}
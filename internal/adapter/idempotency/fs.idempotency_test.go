@@ -0,0 +1,183 @@
+package idempotency
+
+import (
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFSIdempotencyStoreReserve(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	live := func(fingerprint string) port.IdempotencyRecord {
+		return port.IdempotencyRecord{Fingerprint: fingerprint, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	}
+	completed := live("first")
+	completed.Completed = true
+	completed.StatusCode = 201
+	completed.Header = map[string]string{"Content-Type": "application/json"}
+	completed.Body = []byte(`{"id":1}`)
+	expired := live("first")
+	expired.ExpiresAt = now.Add(-time.Second)
+
+	pendingTimeout := constants.IdempotencyPendingTimeout * time.Minute
+
+	tests := []struct {
+		name string
+		// prepare leaves the store as a previous request would
+		prepare      func(t *testing.T, store *FSIdempotencyStore)
+		wantExisting bool
+		wantRecord   port.IdempotencyRecord
+	}{
+		{
+			name:       "new key",
+			prepare:    func(t *testing.T, store *FSIdempotencyStore) {},
+			wantRecord: live("second"),
+		},
+		{
+			name:         "in flight",
+			prepare:      reserve(live("first")),
+			wantExisting: true,
+			wantRecord:   live("first"),
+		},
+		{
+			name:         "completed",
+			prepare:      reserveAndComplete(live("first"), completed),
+			wantExisting: true,
+			wantRecord:   completed,
+		},
+		{
+			name:       "expired",
+			prepare:    reserveAndComplete(live("first"), expired),
+			wantRecord: live("second"),
+		},
+		{
+			name: "same key of another application",
+			prepare: func(t *testing.T, store *FSIdempotencyStore) {
+				if _, _, err := store.Reserve("other-app", "key", live("first")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRecord: live("second"),
+		},
+		{
+			name:         "being written by another bridge",
+			prepare:      writeFile("", time.Now()),
+			wantExisting: true,
+			wantRecord:   port.IdempotencyRecord{Fingerprint: "second"},
+		},
+		{
+			name:       "abandoned while being written",
+			prepare:    writeFile("{", time.Now().Add(-pendingTimeout-time.Minute)),
+			wantRecord: live("second"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFSIdempotencyStore(t.TempDir())
+			tt.prepare(t, store)
+
+			record, existing, err := store.Reserve("app", "key", live("second"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if existing != tt.wantExisting {
+				t.Fatalf("Reserve existing = %v, want %v", existing, tt.wantExisting)
+			}
+			// a record being written has no content yet, only its file's time
+			if tt.wantRecord.CreatedAt.IsZero() {
+				record.CreatedAt = time.Time{}
+			}
+			if !reflect.DeepEqual(record, tt.wantRecord) {
+				t.Fatalf("Reserve = %+v, want %+v", record, tt.wantRecord)
+			}
+		})
+	}
+}
+
+func TestFSIdempotencyStoreComplete(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	pending := port.IdempotencyRecord{Fingerprint: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	completed := pending
+	completed.Completed = true
+	completed.StatusCode = 200
+	completed.Body = []byte("done")
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, store *FSIdempotencyStore)
+	}{
+		{"reserved", reserve(pending)},
+		{"completed before", reserveAndComplete(pending, pending)},
+		{"not reserved", func(t *testing.T, store *FSIdempotencyStore) {
+			if err := os.MkdirAll(filepath.Dir(store.path("app", "key")), 0700); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFSIdempotencyStore(t.TempDir())
+			tt.prepare(t, store)
+
+			if err := store.Complete("app", "key", completed); err != nil {
+				t.Fatal(err)
+			}
+
+			record, existing, err := store.Reserve("app", "key", pending)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !existing || !reflect.DeepEqual(record, completed) {
+				t.Fatalf("Reserve after Complete = %+v, %v, want %+v, true", record, existing, completed)
+			}
+			entries, err := os.ReadDir(filepath.Dir(store.path("app", "key")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%d files in the application's directory, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func reserve(record port.IdempotencyRecord) func(t *testing.T, store *FSIdempotencyStore) {
+	return func(t *testing.T, store *FSIdempotencyStore) {
+		t.Helper()
+		if _, existing, err := store.Reserve("app", "key", record); err != nil || existing {
+			t.Fatalf("Reserve = %v, %v", existing, err)
+		}
+	}
+}
+
+func reserveAndComplete(reserved, completed port.IdempotencyRecord) func(t *testing.T, store *FSIdempotencyStore) {
+	return func(t *testing.T, store *FSIdempotencyStore) {
+		t.Helper()
+		reserve(reserved)(t, store)
+		if err := store.Complete("app", "key", completed); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeFile leaves a record file with raw content, as a bridge that crashed
+// or is still writing would.
+func writeFile(content string, modTime time.Time) func(t *testing.T, store *FSIdempotencyStore) {
+	return func(t *testing.T, store *FSIdempotencyStore) {
+		t.Helper()
+		path := store.path("app", "key")
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
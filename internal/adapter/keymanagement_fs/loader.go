@@ -24,7 +24,7 @@ func (l *FSKeyLoader) LoadPrivateKey(privateKeyPath string) (any, error) {
 	privateKeyBytes, err := os.ReadFile(privateKeyPath)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read private key file: %v", err)
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
 	}
 	privateKeyBlock, _ := pem.Decode(privateKeyBytes)
 	if privateKeyBlock == nil {
@@ -58,6 +58,12 @@ func (l *zapLogger) Fatal(msg string, fields ...interface{}) {
 	l.logger.Fatalw(msg, fields...)
 }
 
+func (l *zapLogger) With(fields ...interface{}) port.Logger {
+	return &zapLogger{
+		logger: l.logger.With(fields...),
+	}
+}
+
 func (l *zapLogger) Sync() error {
 	return l.logger.Sync()
 }
@@ -2,17 +2,40 @@ package adapter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
 	"io"
 	"net/http"
+	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
 	"rapid-bridge/internal/dto/rapid"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/util"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 )
 
-func SendRequestToRapidLinks(logger port.Logger, rapidLinksUrl string, urlPath string, payload rapid.RapidResourceRequest, header http.Header) (rapid.RapidResourceResponse, error) {
+// UnavailableError is returned when the bank's side answered that it cannot
+// serve the request right now, as opposed to rejecting it.
+type UnavailableError struct {
+	StatusCode int
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("rapid links unavailable: status %d", e.StatusCode)
+}
+
+// ErrInvalidResponse is returned when a successful answer of the bank is not
+// a Rapid response at all.
+var ErrInvalidResponse = stderrors.New("invalid response from rapid links")
+
+func SendRequestToRapidLinks(ctx context.Context, client *http.Client, logger port.Logger, rapidLinksUrl string, urlPath string, payload rapid.RapidResourceRequest, header http.Header) (rapid.RapidResourceResponse, error) {
 	var response rapid.RapidResourceResponse
+	logger = util.RequestLogger(ctx, logger)
 
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -20,7 +43,7 @@ func SendRequestToRapidLinks(logger port.Logger, rapidLinksUrl string, urlPath s
 		return response, err
 	}
 
-	req, err := http.NewRequest("POST", rapidLinksUrl+urlPath, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", rapidLinksUrl+urlPath, bytes.NewReader(jsonPayload))
 	if err != nil {
 		logger.Error("Request send to rapid links: Error while creating new http request to %v", rapidLinksUrl, zap.String("error", err.Error()))
 		return response, err
@@ -31,26 +54,62 @@ func SendRequestToRapidLinks(logger port.Logger, rapidLinksUrl string, urlPath s
 			req.Header.Add(name, value)
 		}
 	}
+	// the envelope is JSON whatever the application sent, a GET may have no
+	// content type at all, and is sized by the bridge
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = int64(len(jsonPayload))
+	req.Header.Set("User-Agent", "rapid-bridge/"+constants.Version)
+	req.Header.Set(constants.BridgeVersion, constants.Version)
+	// W3C traceparent of the span in ctx, so that Rapid Links can continue
+	// the trace
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		logger.Error("Request send to rapid links: Error while sending request to rapid links", zap.String("error", err.Error()))
 		return response, err
 	}
 	defer resp.Body.Close()
 
-	logger.Info("Successfully called to Rapid Links", zap.String("url", rapidLinksUrl+urlPath))
-
 	responseBodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logger.Error("Request send to rapid links: Error while reading the response body", zap.String("error", err.Error()))
 		return response, err
 	}
 
+	// a gateway status is the bank's own answer when it comes with an
+	// envelope or a JSON error, and otherwise that of something in between
+	unavailable := func() (rapid.RapidResourceResponse, error) {
+		logger.Warn("Rapid links unavailable", zap.String("url", rapidLinksUrl+urlPath), zap.Int("status_code", resp.StatusCode))
+		return rapid.RapidResourceResponse{}, &UnavailableError{StatusCode: resp.StatusCode}
+	}
+	gatewayStatus := resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout
+
 	if err := json.Unmarshal(responseBodyBytes, &response); err != nil {
+		if gatewayStatus {
+			return unavailable()
+		}
+		if resp.StatusCode >= 400 {
+			// e.g. the error page of a proxy in front of the bank
+			logger.Warn("Rapid links answered an error without a JSON body", zap.String("url", rapidLinksUrl+urlPath), zap.Int("status_code", resp.StatusCode))
+			return response, errors.UpstreamError{UpstreamStatus: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
 		logger.Error("Request send to rapid links: Error while unmarshalling the response body", zap.String("error", err.Error()))
-		return response, err
+		return response, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
 	}
+	response.StatusCode = resp.StatusCode
+
+	// an error with an envelope is signed by the bank and is opened like any
+	// other response, a plain one is passed on as it is
+	if response.Data.Message == "" && (response.Error || resp.StatusCode >= 400) {
+		if gatewayStatus && response.Code == "" && response.Message == "" {
+			return unavailable()
+		}
+		logger.Warn("Rapid links answered with an error", zap.String("url", rapidLinksUrl+urlPath), zap.Int("status_code", resp.StatusCode), zap.String("code", response.Code), zap.String("message", response.Message))
+		return response, errors.UpstreamError{UpstreamStatus: resp.StatusCode, Code: response.Code, Message: response.Message}
+	}
+
+	logger.Info("Successfully called to Rapid Links", zap.String("url", rapidLinksUrl+urlPath), zap.Int("status_code", resp.StatusCode))
 
 	return response, nil
 }
@@ -0,0 +1,79 @@
+package adapter
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"rapid-bridge/internal/adapter/logger"
+	"rapid-bridge/internal/dto/rapid"
+	errors "rapid-bridge/internal/error"
+	"testing"
+)
+
+func TestSendRequestToRapidLinksStatuses(t *testing.T) {
+	envelope := `{"data": {"from": "bank", "to": "app", "message": "c2VhbGVk", "signature": "c2lnbmVk", "key_version": "01M59ZXYWS3P1PF2NDVFGS92HX"}, "error": true}`
+
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		wantUnavailable bool
+		wantUpstream    *errors.UpstreamError
+		wantMessage     string
+	}{
+		{"signed answer", http.StatusOK, envelope, false, nil, "c2VhbGVk"},
+		{"signed error envelope with 503", http.StatusServiceUnavailable, envelope, false, nil, "c2VhbGVk"},
+		{"signed error envelope with 502", http.StatusBadGateway, envelope, false, nil, "c2VhbGVk"},
+		{"json error with 504", http.StatusGatewayTimeout, `{"error": true, "code": "CORE-TIMEOUT", "message": "core banking timed out"}`, false,
+			&errors.UpstreamError{UpstreamStatus: http.StatusGatewayTimeout, Code: "CORE-TIMEOUT", Message: "core banking timed out"}, ""},
+		{"proxy page with 502", http.StatusBadGateway, "<html>Bad Gateway</html>", true, nil, ""},
+		{"empty body with 503", http.StatusServiceUnavailable, "", true, nil, ""},
+		{"json without error with 503", http.StatusServiceUnavailable, `{}`, true, nil, ""},
+		{"proxy page with 500", http.StatusInternalServerError, "<html>Internal Server Error</html>", false,
+			&errors.UpstreamError{UpstreamStatus: http.StatusInternalServerError, Message: "Internal Server Error"}, ""},
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			response, err := SendRequestToRapidLinks(context.Background(), server.Client(), zapLogger, server.URL, "/balance", rapid.RapidResourceRequest{}, http.Header{})
+
+			var unavailable *UnavailableError
+			if isUnavailable := stderrors.As(err, &unavailable); isUnavailable != tt.wantUnavailable {
+				t.Fatalf("error = %v, want UnavailableError %v", err, tt.wantUnavailable)
+			}
+			if tt.wantUnavailable {
+				if unavailable.StatusCode != tt.status {
+					t.Fatalf("UnavailableError status = %d, want %d", unavailable.StatusCode, tt.status)
+				}
+				return
+			}
+
+			if tt.wantUpstream != nil {
+				var upstreamErr errors.UpstreamError
+				if !stderrors.As(err, &upstreamErr) || upstreamErr != *tt.wantUpstream {
+					t.Fatalf("error = %#v, want %#v", err, *tt.wantUpstream)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("error = %v, want nil", err)
+			}
+			if response.StatusCode != tt.status || response.Data.Message != tt.wantMessage {
+				t.Fatalf("response = %d %q, want %d %q", response.StatusCode, response.Data.Message, tt.status, tt.wantMessage)
+			}
+		})
+	}
+}
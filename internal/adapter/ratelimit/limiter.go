@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"rapid-bridge/domain/port"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// a refused concurrent request is told to come back after this long
+const concurrencyRetryAfter = time.Second
+
+type bucket struct {
+	limit port.Limit
+	// nil without a rate limit
+	limiter  *rate.Limiter
+	inFlight int
+}
+
+// Limiters keeps a token bucket and an in-flight count per application, bank
+// and route that has a limit. Limits are looked up on every request, so that
+// a changed limits.json applies at once; a bucket keeps its tokens when its
+// limit changes.
+type Limiters struct {
+	config port.LimitsConfig
+
+	mu      sync.Mutex
+	buckets map[port.LimitKey]*bucket
+}
+
+func NewLimiters(config port.LimitsConfig) *Limiters {
+	return &Limiters{
+		config:  config,
+		buckets: make(map[port.LimitKey]*bucket),
+	}
+}
+
+func (l *Limiters) Acquire(keys []port.LimitKey) (func(), *port.LimitRefusal) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	admitted := []*bucket{}
+	reservations := []*rate.Reservation{}
+	refuse := func(refusal *port.LimitRefusal) (func(), *port.LimitRefusal) {
+		// tokens taken for the other keys are given back
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+		return nil, refusal
+	}
+
+	for _, key := range keys {
+		limit, ok := l.config.Limit(key.Scope, key.Name)
+		if !ok {
+			continue
+		}
+		b := l.bucket(key, limit, now)
+
+		if limit.Concurrency > 0 && b.inFlight >= limit.Concurrency {
+			return refuse(&port.LimitRefusal{Key: key, Reason: port.LimitReasonConcurrency, RetryAfter: concurrencyRetryAfter})
+		}
+
+		if b.limiter != nil {
+			reservation := b.limiter.ReserveN(now, 1)
+			if delay := reservation.DelayFrom(now); !reservation.OK() || delay > 0 {
+				reservation.CancelAt(now)
+				return refuse(&port.LimitRefusal{Key: key, Reason: port.LimitReasonRate, RetryAfter: delay})
+			}
+			reservations = append(reservations, reservation)
+		}
+
+		admitted = append(admitted, b)
+	}
+
+	for _, b := range admitted {
+		b.inFlight++
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for _, b := range admitted {
+				b.inFlight--
+			}
+		})
+	}, nil
+}
+
+func (l *Limiters) bucket(key port.LimitKey, limit port.Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{}
+		l.buckets[key] = b
+	} else if b.limit == limit {
+		return b
+	}
+
+	b.limit = limit
+	switch {
+	case limit.Rate <= 0:
+		b.limiter = nil
+	case b.limiter == nil:
+		b.limiter = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+	default:
+		b.limiter.SetLimitAt(now, rate.Limit(limit.Rate))
+		b.limiter.SetBurstAt(now, limit.Burst)
+	}
+	return b
+}
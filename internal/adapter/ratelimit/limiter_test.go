@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"rapid-bridge/domain/port"
+	"testing"
+)
+
+type limitsConfig map[port.LimitKey]port.Limit
+
+func (c limitsConfig) Limit(scope, name string) (port.Limit, bool) {
+	limit, ok := c[port.LimitKey{Scope: scope, Name: name}]
+	return limit, ok
+}
+
+func TestLimitersAcquire(t *testing.T) {
+	application := port.LimitKey{Scope: port.LimitScopeApplication, Name: "app"}
+	bank := port.LimitKey{Scope: port.LimitScopeBank, Name: "bank"}
+	route := port.LimitKey{Scope: port.LimitScopeRoute, Name: "/balance"}
+
+	// a rate this low refills no token while the test runs
+	const slowRate = 0.001
+
+	type step struct {
+		// release the request admitted last, twice, instead of acquiring
+		release bool
+		keys    []port.LimitKey
+		// empty when the request is admitted
+		wantReason string
+		wantKey    port.LimitKey
+	}
+	admit := func(keys ...port.LimitKey) step { return step{keys: keys} }
+	refuse := func(reason string, key port.LimitKey, keys ...port.LimitKey) step {
+		return step{keys: keys, wantReason: reason, wantKey: key}
+	}
+	release := step{release: true}
+
+	tests := []struct {
+		name   string
+		limits limitsConfig
+		steps  []step
+	}{
+		{
+			name:   "no limits",
+			limits: limitsConfig{},
+			steps:  []step{admit(application, bank, route), admit(application, bank, route), admit(application, bank, route)},
+		},
+		{
+			name:   "concurrency",
+			limits: limitsConfig{application: {Concurrency: 2}},
+			steps: []step{
+				admit(application), admit(application),
+				refuse(port.LimitReasonConcurrency, application, application),
+				release,
+				admit(application),
+			},
+		},
+		{
+			name:   "release twice frees one slot",
+			limits: limitsConfig{application: {Concurrency: 2}},
+			steps: []step{
+				admit(application), admit(application),
+				release,
+				admit(application),
+				refuse(port.LimitReasonConcurrency, application, application),
+			},
+		},
+		{
+			name:   "rate",
+			limits: limitsConfig{bank: {Rate: slowRate, Burst: 2}},
+			steps: []step{
+				admit(bank), admit(bank),
+				refuse(port.LimitReasonRate, bank, bank),
+				release,
+				refuse(port.LimitReasonRate, bank, bank),
+			},
+		},
+		{
+			name:   "limits are per key",
+			limits: limitsConfig{application: {Concurrency: 1}, bank: {Concurrency: 1}},
+			steps: []step{
+				admit(application),
+				admit(bank),
+				admit(route),
+				refuse(port.LimitReasonConcurrency, bank, route, bank),
+			},
+		},
+		{
+			name:   "refused request takes no token",
+			limits: limitsConfig{application: {Rate: slowRate, Burst: 1}, bank: {Concurrency: 1}},
+			steps: []step{
+				admit(bank),
+				refuse(port.LimitReasonConcurrency, bank, application, bank),
+				admit(application),
+			},
+		},
+		{
+			name:   "refused request holds no slot",
+			limits: limitsConfig{application: {Concurrency: 1}, bank: {Rate: slowRate, Burst: 1}},
+			steps: []step{
+				admit(bank),
+				refuse(port.LimitReasonRate, bank, application, bank),
+				admit(application),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiters := NewLimiters(tt.limits)
+
+			held := []func(){}
+			for i, s := range tt.steps {
+				if s.release {
+					held[len(held)-1]()
+					held[len(held)-1]()
+					held = held[:len(held)-1]
+					continue
+				}
+
+				done, refusal := limiters.Acquire(s.keys)
+				if s.wantReason == "" {
+					if refusal != nil {
+						t.Fatalf("step %d: refused by %+v", i, *refusal)
+					}
+					held = append(held, done)
+					continue
+				}
+				if refusal == nil {
+					t.Fatalf("step %d: admitted, want refused for %s by %v", i, s.wantReason, s.wantKey)
+				}
+				if refusal.Reason != s.wantReason || refusal.Key != s.wantKey || refusal.RetryAfter <= 0 {
+					t.Fatalf("step %d: refused by %+v, want %s by %v with a retry after", i, *refusal, s.wantReason, s.wantKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package adapter
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RawResponse is an HTTP response kept byte for byte, for tools that show
+// what was exchanged rather than interpret it.
+type RawResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Duration   time.Duration
+}
+
+// PostJSON posts a JSON body and returns the response as is, whatever its
+// status code.
+func PostJSON(url string, header http.Header, body []byte) (RawResponse, error) {
+	return SendJSON(http.MethodPost, url, header, body)
+}
+
+// SendJSON is PostJSON for any method. A nil body is sent without one.
+func SendJSON(method, url string, header http.Header, body []byte) (RawResponse, error) {
+	return SendJSONWithClient(http.DefaultClient, method, url, header, body)
+}
+
+// SendJSONWithClient is SendJSON through the given client.
+func SendJSONWithClient(client *http.Client, method, url string, header http.Header, body []byte) (RawResponse, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return RawResponse{}, err
+	}
+
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return RawResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return RawResponse{}, err
+	}
+
+	return RawResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       responseBody,
+		Duration:   time.Since(start),
+	}, nil
+}
@@ -7,9 +7,23 @@ import (
 	"errors"
 	"fmt"
 	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/metrics"
 	hybridcrypto "rapid-bridge/pkg/security/crypto"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+var cryptoDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rapid_bridge_crypto_duration_seconds",
+	Help:    "Time taken by RSA key wrapping and unwrapping and by Ed25519 signing and verifying.",
+	Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+}, []string{"operation"})
+
+func observeCrypto(operation string, start time.Time) {
+	cryptoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
 type HybridCryptography struct {
 }
 
@@ -27,7 +41,9 @@ func (a *HybridCryptography) Encrypt(data []byte, applicationRSAPublicKey *rsa.P
 	}
 
 	// Step 3: Encrypt the AES key with RSA-OAEP
+	start := time.Now()
 	encryptedAESKey, err := hybridcrypto.EncryptWithRSA(aesKey, applicationRSAPublicKey)
+	observeCrypto("rsa_wrap", start)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -38,7 +54,9 @@ func (a *HybridCryptography) Encrypt(data []byte, applicationRSAPublicKey *rsa.P
 func (r *HybridCryptography) Decrypt(rsaPrivateKey *rsa.PrivateKey, ciphertext, encryptedAESKey, nonce []byte) ([]byte, error) {
 
 	// Decrypt the AES key using RSA-OAEP
+	start := time.Now()
 	aesKey, err := hybridcrypto.DecryptWithRSA(encryptedAESKey, rsaPrivateKey)
+	observeCrypto("rsa_unwrap", start)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +103,7 @@ func (a *HybridCryptography) CreateDigitalSignature(ed25519PrivateKey ed25519.Pr
 
 	messageToSign := hybridcrypto.CreateMessageToSign(ciphertext, aesKey, nonce)
 
+	defer observeCrypto("sign", time.Now())
 	signature := hybridcrypto.SignWithEd25519(messageToSign, ed25519PrivateKey)
 	base64Signature := base64.StdEncoding.EncodeToString(signature)
 	return base64Signature, nil
@@ -102,6 +121,7 @@ func (a *HybridCryptography) VerifyDigitalSignature(base64EncryptedPayload strin
 	}
 
 	messageToSign := hybridcrypto.CreateMessageToSign(ciphertext, encryptedAESKey, nonce)
+	defer observeCrypto("verify", time.Now())
 	if !ed25519.Verify(senderPublicKey, messageToSign, signature) {
 		return fmt.Errorf("signature verification failed")
 	}
@@ -109,6 +129,26 @@ func (a *HybridCryptography) VerifyDigitalSignature(base64EncryptedPayload strin
 	return nil
 }
 
+func (a *HybridCryptography) Sign(ed25519PrivateKey ed25519.PrivateKey, data []byte) (string, error) {
+	defer observeCrypto("sign", time.Now())
+	signature := hybridcrypto.SignWithEd25519(data, ed25519PrivateKey)
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func (a *HybridCryptography) Verify(data []byte, signatureBase64 string, senderPublicKey ed25519.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(signatureBase64)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	defer observeCrypto("verify", time.Now())
+	if !ed25519.Verify(senderPublicKey, data, signature) {
+		return fmt.Errorf("signature verification failed")
+	}
+
+	return nil
+}
+
 func NewHybridCryptography() port.EncryptionDecryptionInterface {
 	return &HybridCryptography{}
 }
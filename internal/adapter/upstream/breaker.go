@@ -0,0 +1,122 @@
+package upstream
+
+import (
+	"rapid-bridge/domain/port"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Circuit states as reported in health checks.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+type breakerState struct {
+	consecutiveFailures int
+	openedAt            time.Time
+	// set while the one trial request of a half-open circuit is in flight
+	trialStartedAt time.Time
+}
+
+// CircuitBreakers keeps one circuit per bank. After threshold consecutive
+// failed requests the circuit opens and requests to the bank fail fast for
+// cooldown. Then a single trial request is let through: its success closes
+// the circuit, its failure opens it again.
+type CircuitBreakers struct {
+	logger    port.Logger
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	banks map[string]*breakerState
+}
+
+func NewCircuitBreakers(logger port.Logger, threshold int, cooldown time.Duration) *CircuitBreakers {
+	return &CircuitBreakers{
+		logger:    logger,
+		threshold: threshold,
+		cooldown:  cooldown,
+		banks:     make(map[string]*breakerState),
+	}
+}
+
+// Allow reports whether a request to the bank may be sent, and otherwise how
+// long until the next trial request.
+func (b *CircuitBreakers) Allow(bankSlug string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(bankSlug)
+	if state.openedAt.IsZero() {
+		return 0, true
+	}
+
+	now := time.Now()
+	if wait := state.openedAt.Add(b.cooldown).Sub(now); wait > 0 {
+		return wait, false
+	}
+
+	// a trial that never reported back, e.g. because the caller went away,
+	// does not keep the circuit half-open forever
+	if !state.trialStartedAt.IsZero() && now.Sub(state.trialStartedAt) < b.cooldown {
+		return state.trialStartedAt.Add(b.cooldown).Sub(now), false
+	}
+
+	state.trialStartedAt = now
+	return 0, true
+}
+
+func (b *CircuitBreakers) RecordSuccess(bankSlug string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(bankSlug)
+	if !state.openedAt.IsZero() {
+		b.logger.Info("Circuit closed, bank is answering again", zap.String("bank", bankSlug))
+	}
+
+	*state = breakerState{}
+}
+
+func (b *CircuitBreakers) RecordFailure(bankSlug string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(bankSlug)
+	state.consecutiveFailures++
+
+	halfOpen := !state.trialStartedAt.IsZero()
+	if halfOpen || (state.openedAt.IsZero() && state.consecutiveFailures >= b.threshold) {
+		state.openedAt = time.Now()
+		state.trialStartedAt = time.Time{}
+		b.logger.Warn("Circuit opened, failing requests to bank fast", zap.String("bank", bankSlug), zap.Int("consecutive_failures", state.consecutiveFailures), zap.String("cooldown", b.cooldown.String()))
+	}
+}
+
+func (b *CircuitBreakers) State(bankSlug string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(bankSlug)
+	switch {
+	case state.openedAt.IsZero():
+		return CircuitClosed
+	case time.Since(state.openedAt) < b.cooldown:
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+func (b *CircuitBreakers) state(bankSlug string) *breakerState {
+	state, ok := b.banks[bankSlug]
+	if !ok {
+		state = &breakerState{}
+		b.banks[bankSlug] = state
+	}
+	return state
+}
@@ -0,0 +1,79 @@
+package upstream
+
+import (
+	"rapid-bridge/internal/adapter/logger"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	const (
+		threshold = 3
+		cooldown  = 50 * time.Millisecond
+	)
+
+	const (
+		allow   = "allow"
+		success = "success"
+		failure = "failure"
+		wait    = "wait"
+	)
+
+	tests := []struct {
+		name      string
+		events    []string
+		wantState string
+		wantAllow bool
+	}{
+		{"new", nil, CircuitClosed, true},
+		{"below threshold", []string{failure, failure}, CircuitClosed, true},
+		{"success resets failures", []string{failure, failure, success, failure, failure}, CircuitClosed, true},
+		{"opens at threshold", []string{failure, failure, failure}, CircuitOpen, false},
+		{"half-open after cooldown", []string{failure, failure, failure, wait}, CircuitHalfOpen, true},
+		{"one trial at a time", []string{failure, failure, failure, wait, allow}, CircuitHalfOpen, false},
+		{"trial success closes", []string{failure, failure, failure, wait, allow, success}, CircuitClosed, true},
+		{"trial failure opens again", []string{failure, failure, failure, wait, allow, failure}, CircuitOpen, false},
+		{"unanswered trial expires", []string{failure, failure, failure, wait, allow, wait}, CircuitHalfOpen, true},
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			breakers := NewCircuitBreakers(zapLogger, threshold, cooldown)
+			for _, event := range tt.events {
+				switch event {
+				case allow:
+					if _, ok := breakers.Allow("bank"); !ok {
+						t.Fatal("trial request not allowed")
+					}
+				case success:
+					breakers.RecordSuccess("bank")
+				case failure:
+					breakers.RecordFailure("bank")
+				case wait:
+					time.Sleep(cooldown + 10*time.Millisecond)
+				}
+			}
+
+			if state := breakers.State("bank"); state != tt.wantState {
+				t.Fatalf("State = %s, want %s", state, tt.wantState)
+			}
+			wait, ok := breakers.Allow("bank")
+			if ok != tt.wantAllow {
+				t.Fatalf("Allow = %v, want %v", ok, tt.wantAllow)
+			}
+			if !ok && (wait <= 0 || wait > cooldown) {
+				t.Fatalf("Allow wait = %s, want within (0, %s]", wait, cooldown)
+			}
+			if state := breakers.State("other-bank"); state != CircuitClosed {
+				t.Fatalf("State of another bank = %s, want %s", state, CircuitClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package upstream
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/security/tlsconfig"
+	"rapid-bridge/pkg/util"
+	"sync"
+	"time"
+)
+
+type cachedClient struct {
+	stamp  util.FileStamp
+	client *http.Client
+}
+
+// ClientCache keeps one HTTP client per distinct upstream TLS configuration,
+// so that connections to a bank are reused across requests. A client is
+// rebuilt when one of the files its configuration names changes, which picks
+// up renewed client certificates and CA bundles without a restart.
+type ClientCache struct {
+	mu      sync.Mutex
+	clients map[string]cachedClient
+}
+
+func NewClientCache() *ClientCache {
+	return &ClientCache{
+		clients: make(map[string]cachedClient),
+	}
+}
+
+func (c *ClientCache) Client(upstreamTLS *port.UpstreamTLS) (*http.Client, error) {
+	if upstreamTLS == nil {
+		return defaultClient, nil
+	}
+
+	settings, err := json.Marshal(upstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+	key := string(settings)
+	stamp := util.StatFiles(upstreamTLS.CAFile, upstreamTLS.CertFile, upstreamTLS.KeyFile)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.clients[key]; ok && stamp.Unchanged(cached.stamp) {
+		return cached.client, nil
+	}
+
+	client, err := NewHTTPClient(upstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+	if previous, ok := c.clients[key]; ok {
+		previous.client.CloseIdleConnections()
+	}
+	c.clients[key] = cachedClient{stamp: stamp, client: client}
+
+	return client, nil
+}
+
+// defaultClient is used for banks without TLS settings.
+var defaultClient = &http.Client{Transport: newTransport(nil)}
+
+// NewHTTPClient builds a client with its own transport for the given TLS
+// settings, or returns the shared default client for nil. The client has no
+// overall timeout: callers bound each request with its context.
+func NewHTTPClient(upstreamTLS *port.UpstreamTLS) (*http.Client, error) {
+	if upstreamTLS == nil {
+		return defaultClient, nil
+	}
+
+	tlsConfig, err := tlsconfig.NewClientConfig(*upstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: newTransport(tlsConfig)}, nil
+}
+
+// newTransport bounds connecting and the TLS handshake, so that an
+// unreachable bank fails over to the next endpoint quickly.
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   constants.UpstreamConnectTimeout * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = constants.UpstreamTLSHandshakeTimeout * time.Second
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
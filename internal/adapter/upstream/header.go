@@ -0,0 +1,69 @@
+package upstream
+
+import (
+	"net/http"
+	"rapid-bridge/constants"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// routingHeaders are forwarded to every bank, as Rapid Links routes on them.
+var routingHeaders = []string{constants.From, constants.To, constants.KeyVersion}
+
+// hopByHopHeaders only apply to a single connection and are never forwarded.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// bridgeHeaders describe the request the bridge sends, not the one the
+// application sent, and are set by the bridge alone.
+var bridgeHeaders = []string{
+	echo.HeaderContentType,
+	echo.HeaderContentLength,
+	echo.HeaderContentEncoding,
+	"Host",
+	"User-Agent",
+	echo.HeaderXRequestID,
+	constants.BridgeVersion,
+	// the trace context is that of the bridge's own span
+	"Traceparent",
+	"Tracestate",
+}
+
+// ForwardHeader returns the headers of an application's request that may be
+// forwarded to the bank: the routing headers and the allowed ones. Hop-by-hop
+// headers, including those listed in Connection, and the headers the bridge
+// sets itself are dropped even when allowed.
+func ForwardHeader(inbound http.Header, allowed []string) http.Header {
+	dropped := map[string]bool{}
+	for _, name := range append(hopByHopHeaders, bridgeHeaders...) {
+		dropped[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, connection := range inbound.Values("Connection") {
+		for _, name := range strings.Split(connection, ",") {
+			dropped[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	header := http.Header{}
+	for _, name := range append(routingHeaders, allowed...) {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if dropped[name] || header[name] != nil {
+			continue
+		}
+		if values := inbound.Values(name); len(values) > 0 {
+			header[name] = append([]string(nil), values...)
+		}
+	}
+
+	return header
+}
@@ -0,0 +1,90 @@
+package upstream
+
+import (
+	"net/http"
+	"rapid-bridge/constants"
+	"reflect"
+	"testing"
+)
+
+func TestForwardHeader(t *testing.T) {
+	routing := http.Header{
+		constants.From:       {"app"},
+		constants.To:         {"bank"},
+		constants.KeyVersion: {"01M59ZXYWS3P1PF2NDVFGS92HX"},
+	}
+	withRouting := func(header http.Header) http.Header {
+		merged := routing.Clone()
+		for name, values := range header {
+			merged[name] = values
+		}
+		return merged
+	}
+
+	tests := []struct {
+		name    string
+		inbound http.Header
+		allowed []string
+		want    http.Header
+	}{
+		{
+			name:    "routing headers only",
+			inbound: withRouting(http.Header{"X-Branch": {"12"}, "Authorization": {"Bearer secret"}}),
+			want:    routing,
+		},
+		{
+			name:    "allowed header",
+			inbound: withRouting(http.Header{"X-Branch": {"12", "13"}, "X-Other": {"1"}}),
+			allowed: []string{"X-Branch"},
+			want:    withRouting(http.Header{"X-Branch": {"12", "13"}}),
+		},
+		{
+			name:    "allowed names are canonicalized",
+			inbound: withRouting(http.Header{"X-Branch": {"12"}}),
+			allowed: []string{" x-branch "},
+			want:    withRouting(http.Header{"X-Branch": {"12"}}),
+		},
+		{
+			name:    "allowed but absent",
+			inbound: routing,
+			allowed: []string{"X-Branch"},
+			want:    routing,
+		},
+		{
+			name:    "hop-by-hop dropped even when allowed",
+			inbound: withRouting(http.Header{"Connection": {"close"}, "Te": {"trailers"}, "Upgrade": {"h2c"}}),
+			allowed: []string{"Connection", "Te", "Upgrade"},
+			want:    routing,
+		},
+		{
+			name:    "headers listed in connection dropped",
+			inbound: withRouting(http.Header{"Connection": {"X-Branch, X-Hop"}, "X-Branch": {"12"}, "X-Hop": {"1"}}),
+			allowed: []string{"X-Branch", "X-Hop"},
+			want:    routing,
+		},
+		{
+			name:    "bridge headers dropped even when allowed",
+			inbound: withRouting(http.Header{"Content-Type": {"text/plain"}, "Traceparent": {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}, "X-Request-Id": {"forged"}}),
+			allowed: []string{"Content-Type", "Traceparent", "X-Request-Id"},
+			want:    routing,
+		},
+		{
+			name:    "routing header listed in connection dropped",
+			inbound: withRouting(http.Header{"Connection": {constants.To}}),
+			want:    http.Header{constants.From: routing[constants.From], constants.KeyVersion: routing[constants.KeyVersion]},
+		},
+		{
+			name:    "allowed twice",
+			inbound: withRouting(http.Header{"X-Branch": {"12"}}),
+			allowed: []string{"X-Branch", "x-branch"},
+			want:    withRouting(http.Header{"X-Branch": {"12"}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ForwardHeader(tt.inbound, tt.allowed); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ForwardHeader = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
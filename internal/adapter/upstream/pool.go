@@ -0,0 +1,214 @@
+package upstream
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"rapid-bridge/domain/port"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type endpointState struct {
+	healthy             bool
+	consecutiveFailures int
+	lastFailure         time.Time
+}
+
+type EndpointPool struct {
+	logger       port.Logger
+	probeTimeout time.Duration
+
+	mu        sync.RWMutex
+	endpoints map[string]*endpointState
+}
+
+func NewEndpointPool(logger port.Logger, probeTimeout time.Duration) *EndpointPool {
+	return &EndpointPool{
+		logger:       logger,
+		probeTimeout: probeTimeout,
+		endpoints:    make(map[string]*endpointState),
+	}
+}
+
+func (p *EndpointPool) Order(urls []string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := make([]string, 0, len(urls))
+	unhealthy := make([]string, 0)
+
+	for _, u := range urls {
+		state, ok := p.endpoints[u]
+		if !ok {
+			state = &endpointState{healthy: true}
+			p.endpoints[u] = state
+		}
+
+		if state.healthy {
+			healthy = append(healthy, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
+	}
+
+	// unhealthy endpoints are still tried last so that a request is not
+	// rejected only because every probe failed at the same time
+	return append(healthy, unhealthy...)
+}
+
+func (p *EndpointPool) MarkSuccess(u string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.endpoints[u]
+	if !ok {
+		state = &endpointState{}
+		p.endpoints[u] = state
+	}
+
+	if !state.healthy {
+		p.logger.Info("Upstream endpoint is healthy again", zap.String("url", u))
+	}
+
+	state.healthy = true
+	state.consecutiveFailures = 0
+}
+
+func (p *EndpointPool) MarkFailure(u string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.endpoints[u]
+	if !ok {
+		state = &endpointState{}
+		p.endpoints[u] = state
+	}
+
+	if state.healthy {
+		p.logger.Warn("Upstream endpoint marked unhealthy", zap.String("url", u), zap.String("error", err.Error()))
+	}
+
+	state.healthy = false
+	state.consecutiveFailures++
+	state.lastFailure = time.Now()
+}
+
+// IsHealthy reports the last known health of the endpoint. An endpoint that
+// was never used counts as healthy, and is probed from then on.
+func (p *EndpointPool) IsHealthy(u string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.endpoints[u]
+	if !ok {
+		state = &endpointState{healthy: true}
+		p.endpoints[u] = state
+	}
+	return state.healthy
+}
+
+// StartHealthChecks probes every known endpoint on the given interval and
+// updates its health. The returned function stops the probing.
+func (p *EndpointPool) StartHealthChecks(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				p.probeAll()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (p *EndpointPool) probeAll() {
+	p.mu.RLock()
+	urls := make([]string, 0, len(p.endpoints))
+	for u := range p.endpoints {
+		urls = append(urls, u)
+	}
+	p.mu.RUnlock()
+
+	for _, u := range urls {
+		if err := p.probe(u); err != nil {
+			p.MarkFailure(u, err)
+			continue
+		}
+		p.MarkSuccess(u)
+	}
+}
+
+// probe checks that a TCP connection can be opened to the endpoint host
+func (p *EndpointPool) probe(rawUrl string) error {
+	address, err := DialAddress(rawUrl)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", address, p.probeTimeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// DialAddress returns the host:port to dial for an upstream url, using the
+// scheme default port when none is given.
+func DialAddress(rawUrl string) (string, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if parsedUrl.Host == "" {
+		return "", errors.New("upstream url has no host: " + rawUrl)
+	}
+
+	port := parsedUrl.Port()
+	if port == "" {
+		port = "80"
+		if parsedUrl.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return net.JoinHostPort(parsedUrl.Hostname(), port), nil
+}
+
+// IsConnectError reports whether the error happened before the request
+// reached the upstream, which makes it safe to retry on another endpoint.
+func IsConnectError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial"
+	}
+
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// Backoff is the delay before retry number attempt+1: a random duration up
+// to base doubled per attempt, capped at max, so that callers failing at the
+// same moment do not retry in lockstep.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	ceiling := base << attempt
+	if ceiling > max || ceiling <= 0 {
+		ceiling = max
+	}
+	return rand.N(ceiling)
+}
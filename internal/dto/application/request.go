@@ -4,4 +4,7 @@ type ResourceRequest struct {
 	Message  string  `json:"message"`
 	TOTPCode *string `json:"totpCode"`
 	Username *string `json:"username"`
+
+	// query parameters of the application request, forwarded encrypted
+	Query map[string][]string `json:"query,omitempty"`
 }
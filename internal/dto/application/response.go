@@ -2,6 +2,8 @@ package application
 
 type ResourceResponse struct {
 	Message string `json:"message"`
+	// the bank's HTTP status, passed on with its signed payload
+	StatusCode int `json:"-"`
 }
 
 type OtherResponse struct {
@@ -1,9 +1,62 @@
 package rapid
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 type RapidResourceRequest struct {
 	From       string `json:"from" validate:"required"`
 	To         string `json:"to" validate:"required"`
 	Message    string `json:"message" validate:"required"` // Format: base64(ciphertext)-base64(encryptedAESKey)-base64(nonce)
 	Signature  string `json:"signature" validate:"required"`
 	KeyVersion string `json:"key_version" validate:"required"`
+
+	Metadata          *EnvelopeMetadata `json:"metadata,omitempty"`
+	MetadataSignature string            `json:"metadata_signature,omitempty"` // base64 Ed25519 signature of Metadata.SigningBytes(Message)
+}
+
+// EnvelopeMetadata describes the application request an envelope stands
+// for. Envelopes are always POSTed to Rapid Links; the original method is
+// carried here and the query parameters inside the encrypted payload, so
+// that neither appears in the clear URL.
+type EnvelopeMetadata struct {
+	Method string `json:"method"`
+	// upstream path the envelope was sent to
+	Path string `json:"path"`
+	// the decrypted payload has a "query" object with the query parameters
+	QueryInPayload bool `json:"query_in_payload,omitempty"`
+	// id of the application request, as in the bridge's logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+const envelopeMetadataVersion = "rapid-envelope-metadata/v1"
+
+// metadata with a request id is signed in version 2, so that envelopes
+// without one keep verifying with version 1
+const envelopeMetadataVersionRequestID = "rapid-envelope-metadata/v2"
+
+// ErrInvalidMetadata is returned for metadata that cannot be signed, because a
+// field contains a line break or another control character.
+var ErrInvalidMetadata = errors.New("envelope metadata fields must not contain control characters")
+
+// SigningBytes binds the metadata to the message it travels with, so that it
+// can neither be changed in transit nor moved to another envelope. The fields
+// are joined with line breaks, so metadata whose fields contain one could be
+// read back as other metadata, and is refused.
+func (m EnvelopeMetadata) SigningBytes(message string) ([]byte, error) {
+	fields := []string{envelopeMetadataVersion, m.Method, m.Path, strconv.FormatBool(m.QueryInPayload), message}
+	if m.RequestID != "" {
+		fields = []string{envelopeMetadataVersionRequestID, m.Method, m.Path, strconv.FormatBool(m.QueryInPayload), m.RequestID, message}
+	}
+
+	for _, field := range fields {
+		if strings.ContainsFunc(field, unicode.IsControl) {
+			return nil, ErrInvalidMetadata
+		}
+	}
+
+	return []byte(strings.Join(fields, "\n")), nil
 }
@@ -0,0 +1,59 @@
+package rapid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSigningBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata EnvelopeMetadata
+		want     string
+	}{
+		{
+			name:     "v1",
+			metadata: EnvelopeMetadata{Method: "GET", Path: "/status", QueryInPayload: true},
+			want:     "rapid-envelope-metadata/v1\nGET\n/status\ntrue\nmessage",
+		},
+		{
+			name:     "v2 with request id",
+			metadata: EnvelopeMetadata{Method: "POST", Path: "/transfer", RequestID: "01J9Z"},
+			want:     "rapid-envelope-metadata/v2\nPOST\n/transfer\nfalse\n01J9Z\nmessage",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.metadata.SigningBytes("message")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("SigningBytes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSigningBytesRejectsControlCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata EnvelopeMetadata
+		message  string
+	}{
+		// would sign the same bytes as Path "a", QueryInPayload false and
+		// RequestID "true\n01J9Z"
+		{"newline in path", EnvelopeMetadata{Method: "GET", Path: "a\nfalse", QueryInPayload: true, RequestID: "01J9Z"}, "message"},
+		{"newline in request id", EnvelopeMetadata{Method: "GET", Path: "a", RequestID: "true\n01J9Z"}, "message"},
+		{"newline in method", EnvelopeMetadata{Method: "GET\n/a", Path: "/b"}, "message"},
+		{"carriage return in path", EnvelopeMetadata{Method: "GET", Path: "/a\r"}, "message"},
+		{"newline in message", EnvelopeMetadata{Method: "GET", Path: "/a"}, "message\nmore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.metadata.SigningBytes(tt.message); !errors.Is(err, ErrInvalidMetadata) {
+				t.Fatalf("SigningBytes = %v, want ErrInvalidMetadata", err)
+			}
+		})
+	}
+}
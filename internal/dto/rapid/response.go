@@ -9,4 +9,10 @@ type RapidResourceResponse struct {
 		KeyVersion string `json:"key_version"`
 	} `json:"data"`
 	Error bool `json:"error"`
+	// set on plain error answers, which carry no envelope
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// HTTP status the bank answered with
+	StatusCode int `json:"-"`
 }
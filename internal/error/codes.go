@@ -0,0 +1,137 @@
+package error
+
+import (
+	stderrors "errors"
+	"net/http"
+)
+
+// Code is a stable, machine-readable error code. Every error response of the
+// bridge carries one, so that applications never have to parse messages.
+type Code string
+
+// Codes starting with BRIDGE- are about the request or the bridge itself,
+// those starting with UPSTREAM- about the bank's Rapid Links.
+const (
+	CodeInvalidRequest        Code = "BRIDGE-INVALID-REQUEST"
+	CodeHeaderMissing         Code = "BRIDGE-HEADER-MISSING"
+	CodeNotFound              Code = "BRIDGE-NOT-FOUND"
+	CodeMethodNotAllowed      Code = "BRIDGE-METHOD-NOT-ALLOWED"
+	CodeAuthRequired          Code = "BRIDGE-AUTH-REQUIRED"
+	CodeAuthInvalid           Code = "BRIDGE-AUTH-INVALID"
+	CodeKeyExpired            Code = "BRIDGE-KEY-EXPIRED"
+	CodeCertRequired          Code = "BRIDGE-CERT-REQUIRED"
+	CodeCertUnmapped          Code = "BRIDGE-CERT-UNMAPPED"
+	CodeSourceMismatch        Code = "BRIDGE-SOURCE-MISMATCH"
+	CodeAppNotRegistered      Code = "BRIDGE-APP-NOT-REGISTERED"
+	CodeAppDisabled           Code = "BRIDGE-APP-DISABLED"
+	CodeKeyVersionUnknown     Code = "BRIDGE-KEY-VERSION-UNKNOWN"
+	CodeBankNotFound          Code = "BRIDGE-BANK-NOT-FOUND"
+	CodeRouteNotEnabled       Code = "BRIDGE-ROUTE-NOT-ENABLED"
+	CodeConfigError           Code = "BRIDGE-CONFIG-ERROR"
+	CodeIdempotencyKeyReused  Code = "BRIDGE-IDEMPOTENCY-KEY-REUSED"
+	CodeIdempotencyInProgress Code = "BRIDGE-IDEMPOTENCY-IN-PROGRESS"
+	CodeRateLimited           Code = "BRIDGE-RATE-LIMITED"
+	CodeConcurrencyLimited    Code = "BRIDGE-CONCURRENCY-LIMITED"
+	CodeSigInvalid            Code = "BRIDGE-SIG-INVALID"
+	CodeInternal              Code = "BRIDGE-INTERNAL"
+
+	CodeUpstreamError           Code = "UPSTREAM-ERROR"
+	CodeUpstreamUnavailable     Code = "UPSTREAM-UNAVAILABLE"
+	CodeUpstreamTimeout         Code = "UPSTREAM-TIMEOUT"
+	CodeUpstreamCircuitOpen     Code = "UPSTREAM-CIRCUIT-OPEN"
+	CodeUpstreamInvalidResponse Code = "UPSTREAM-INVALID-RESPONSE"
+)
+
+type codeDefinition struct {
+	status int
+	title  string
+}
+
+var catalogue = map[Code]codeDefinition{
+	CodeInvalidRequest:        {http.StatusBadRequest, "Invalid request"},
+	CodeHeaderMissing:         {http.StatusBadRequest, "Required header missing"},
+	CodeNotFound:              {http.StatusNotFound, "Not found"},
+	CodeMethodNotAllowed:      {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodeAuthRequired:          {http.StatusUnauthorized, "Authentication required"},
+	CodeAuthInvalid:           {http.StatusUnauthorized, "Invalid API key"},
+	CodeKeyExpired:            {http.StatusUnauthorized, "API key expired"},
+	CodeCertRequired:          {http.StatusUnauthorized, "Client certificate required"},
+	CodeCertUnmapped:          {http.StatusForbidden, "Client certificate not mapped"},
+	CodeSourceMismatch:        {http.StatusForbidden, "Source application mismatch"},
+	CodeAppNotRegistered:      {http.StatusForbidden, "Application not registered"},
+	CodeAppDisabled:           {http.StatusForbidden, "Application disabled"},
+	CodeKeyVersionUnknown:     {http.StatusBadRequest, "Unknown key version"},
+	CodeBankNotFound:          {http.StatusNotFound, "Bank not found"},
+	CodeRouteNotEnabled:       {http.StatusForbidden, "Route not enabled for bank"},
+	CodeConfigError:           {http.StatusInternalServerError, "Bridge misconfigured"},
+	CodeIdempotencyKeyReused:  {http.StatusUnprocessableEntity, "Idempotency key reused"},
+	CodeIdempotencyInProgress: {http.StatusConflict, "Request in progress"},
+	CodeRateLimited:           {http.StatusTooManyRequests, "Rate limit exceeded"},
+	CodeConcurrencyLimited:    {http.StatusTooManyRequests, "Too many concurrent requests"},
+	CodeSigInvalid:            {http.StatusBadGateway, "Response signature invalid"},
+	CodeInternal:              {http.StatusInternalServerError, "Internal error"},
+
+	CodeUpstreamError:           {http.StatusBadGateway, "Bank answered with an error"},
+	CodeUpstreamUnavailable:     {http.StatusBadGateway, "Bank unavailable"},
+	CodeUpstreamTimeout:         {http.StatusGatewayTimeout, "Bank did not answer in time"},
+	CodeUpstreamCircuitOpen:     {http.StatusServiceUnavailable, "Bank failing, retry later"},
+	CodeUpstreamInvalidResponse: {http.StatusBadGateway, "Invalid response from bank"},
+}
+
+// Status is the HTTP status the code is answered with.
+func (c Code) Status() int {
+	if definition, ok := catalogue[c]; ok {
+		return definition.status
+	}
+	return http.StatusInternalServerError
+}
+
+func (c Code) Title() string {
+	if definition, ok := catalogue[c]; ok {
+		return definition.title
+	}
+	return http.StatusText(c.Status())
+}
+
+// CodeForStatus is the code of errors that were raised with a status only,
+// such as the ones of echo itself.
+func CodeForStatus(status int) Code {
+	switch {
+	case status == http.StatusUnauthorized:
+		return CodeAuthRequired
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case status == http.StatusTooManyRequests:
+		return CodeRateLimited
+	case status == http.StatusBadGateway:
+		return CodeUpstreamUnavailable
+	case status == http.StatusServiceUnavailable:
+		return CodeUpstreamCircuitOpen
+	case status == http.StatusGatewayTimeout:
+		return CodeUpstreamTimeout
+	case status >= 400 && status < 500:
+		return CodeInvalidRequest
+	default:
+		return CodeInternal
+	}
+}
+
+// NewCodedError returns an error answered with the status of its code.
+func NewCodedError(code Code, message string) error {
+	return RapidLinksError{
+		StatusCode: code.Status(),
+		Message:    message,
+		Code:       code,
+	}
+}
+
+// CodeOf returns the code of the first coded error in err's chain.
+func CodeOf(err error) (Code, bool) {
+	var rapidLinksError RapidLinksError
+	if stderrors.As(err, &rapidLinksError) && rapidLinksError.Code != "" {
+		return rapidLinksError.Code, true
+	}
+	return "", false
+}
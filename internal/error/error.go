@@ -5,6 +5,8 @@ import "fmt"
 type RapidLinksError struct {
 	StatusCode int
 	Message    string
+	// empty for errors raised with a status only
+	Code Code
 }
 
 func (e RapidLinksError) Error() string {
@@ -15,9 +17,41 @@ func (e RapidLinksError) GetStatusCode() int {
 	return e.StatusCode
 }
 
+func (e RapidLinksError) GetCode() Code {
+	if e.Code == "" {
+		return CodeForStatus(e.StatusCode)
+	}
+	return e.Code
+}
+
 func NewRapidLinksError(message string, statusCode int) error {
 	return RapidLinksError{
 		StatusCode: statusCode,
 		Message:    message,
 	}
 }
+
+// UpstreamError is an error the bank answered without a signed envelope. Its
+// code and message are passed on to the application as they are.
+type UpstreamError struct {
+	// status the bank answered with
+	UpstreamStatus int
+	Code           string
+	Message        string
+}
+
+func (e UpstreamError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("rapid links answered %d - %s: %s", e.UpstreamStatus, e.Code, e.Message)
+	}
+	return fmt.Sprintf("rapid links answered %d - %s", e.UpstreamStatus, e.Message)
+}
+
+// GetStatusCode is the status for the application: the bank's own for client
+// errors, 502 for its server errors.
+func (e UpstreamError) GetStatusCode() int {
+	if e.UpstreamStatus >= 400 && e.UpstreamStatus < 500 {
+		return e.UpstreamStatus
+	}
+	return 502
+}
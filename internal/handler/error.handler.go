@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"rapid-bridge/domain/port"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// set when the bank answered with a plain error
+	UpstreamStatus int    `json:"upstream_status,omitempty"`
+	UpstreamCode   string `json:"upstream_code,omitempty"`
+}
+
+// NewErrorHandler renders every error leaving the bridge as
+// application/problem+json with a code from the catalogue in
+// rapid-bridge/internal/error. Details of unexpected errors are logged and
+// never sent to the caller.
+func NewErrorHandler(logger port.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		logger := util.RequestLogger(c.Request().Context(), logger)
+
+		p := newProblem(err)
+		if p.Code == string(errors.CodeInternal) {
+			logger.Error("Request failed", zap.String("method", c.Request().Method), zap.String("uri", c.Request().RequestURI), zap.String("error", err.Error()))
+			p.Detail = ""
+		}
+		p.Instance = c.Request().URL.Path
+		p.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+		if p.RequestID == "" {
+			p.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(p.Status)
+		} else {
+			var body []byte
+			body, err = json.Marshal(p)
+			if err == nil {
+				err = c.Blob(p.Status, mimeApplicationProblemJSON, body)
+			}
+		}
+		if err != nil {
+			logger.Error("Failed to send error response", zap.String("error", err.Error()))
+		}
+	}
+}
+
+func newProblem(err error) problem {
+	var rapidLinksError errors.RapidLinksError
+	var upstreamErr errors.UpstreamError
+	var httpErr *echo.HTTPError
+
+	switch {
+	case stderrors.As(err, &rapidLinksError):
+		return codedProblem(rapidLinksError.GetCode(), rapidLinksError.GetStatusCode(), rapidLinksError.Message)
+	case stderrors.As(err, &upstreamErr):
+		p := codedProblem(errors.CodeUpstreamError, upstreamErr.GetStatusCode(), upstreamErr.Message)
+		p.UpstreamStatus = upstreamErr.UpstreamStatus
+		p.UpstreamCode = upstreamErr.Code
+		return p
+	case stderrors.As(err, &httpErr):
+		detail := ""
+		if message, ok := httpErr.Message.(string); ok {
+			detail = message
+		} else if httpErr.Message != nil {
+			detail = fmt.Sprint(httpErr.Message)
+		}
+		return codedProblem(errors.CodeForStatus(httpErr.Code), httpErr.Code, detail)
+	default:
+		return codedProblem(errors.CodeInternal, http.StatusInternalServerError, "")
+	}
+}
+
+func codedProblem(code errors.Code, status int, detail string) problem {
+	return problem{
+		Type:   "urn:rapid-bridge:problem:" + string(code),
+		Title:  code.Title(),
+		Status: status,
+		Detail: detail,
+		Code:   string(code),
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"rapid-bridge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	healthService *service.HealthService
+}
+
+func NewHealthHandler(healthService *service.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+// HandleHealth always answers 200 while the bridge runs, so that a load
+// balancer does not take it out of rotation because one bank is down. The
+// status field says whether every bank is reachable.
+func (h *HealthHandler) HandleHealth(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.healthService.Report())
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"rapid-bridge/domain/port"
 	"rapid-bridge/internal/service"
 )
 
@@ -22,8 +23,8 @@ func (k *KeyHandler) HandleBankExistingKeys(bankSlug, rsaPublicKeyPath, ed25519P
 	return k.Service.UseExistingBankKeys(bankSlug, rsaPublicKeyPath, ed25519PublicKeyPath)
 }
 
-func (k *KeyHandler) HandleBankFetchKeys(rapidUrl, bankSlug string) error {
-	return k.Service.FetchAndSaveBankKeys(rapidUrl, bankSlug)
+func (k *KeyHandler) HandleBankFetchKeys(rapidUrls []string, bankSlug string, upstreamTLS *port.UpstreamTLS) error {
+	return k.Service.FetchAndSaveBankKeys(rapidUrls, bankSlug, upstreamTLS)
 }
 
 func NewKeyHandler(service *service.KeyService) *KeyHandler {
@@ -28,11 +28,11 @@ func (h *PlaygroundHandler) HandleApplicationRegister(c echo.Context) error {
 
 	if err := c.Bind(&request); err != nil {
 		h.logger.Error("Validation Error: Request payload does not follow proper format", zap.String("error", err.Error()))
-		return errors.NewRapidLinksError(err.Error(), 400)
+		return errors.NewCodedError(errors.CodeInvalidRequest, err.Error())
 	}
 	if err := c.Validate(request); err != nil {
 		h.logger.Error("Validation Error: Request payload does not follow proper format", zap.String("error", err.Error()))
-		return errors.NewRapidLinksError(err.Error(), 400)
+		return errors.NewCodedError(errors.CodeInvalidRequest, err.Error())
 	}
 
 	response, err := h.playgroundService.RegisterApplication(request)
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
+	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
 	"rapid-bridge/internal/dto/application"
 	errors "rapid-bridge/internal/error"
 	service "rapid-bridge/internal/service"
+	"rapid-bridge/pkg/util"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -13,44 +17,62 @@ import (
 
 type resourceHandler struct {
 	logger               port.Logger
+	routes               port.RouteTable
 	RapidResourceService *service.RapidResourceService
 }
 
 func (r *resourceHandler) HandleResource(c echo.Context) error {
+	path := strings.TrimPrefix(c.Request().URL.Path, strings.TrimSuffix(constants.ResourcePathPrefix, "/"))
+
+	route, ok := r.routes.Match(c.Request().Method, path)
+	if !ok {
+		if methods := r.routes.Methods(path); len(methods) > 0 {
+			c.Response().Header().Set(echo.HeaderAllow, strings.Join(methods, ", "))
+			return errors.NewCodedError(errors.CodeMethodNotAllowed, fmt.Sprintf("method %s is not routed for %s", c.Request().Method, path))
+		}
+		return errors.NewCodedError(errors.CodeNotFound, fmt.Sprintf("no resource route for %s", path))
+	}
+
+	logger := util.RequestLogger(c.Request().Context(), r.logger)
 	request := application.ResourceRequest{}
 
 	if err := c.Bind(&request); err != nil {
-		r.logger.Error("Validation Error: Request payload does not follow proper format", zap.String("error", err.Error()))
-		return errors.NewRapidLinksError(err.Error(), 400)
+		logger.Error("Validation Error: Request payload does not follow proper format", zap.String("error", err.Error()))
+		return errors.NewCodedError(errors.CodeInvalidRequest, "request body is not valid JSON")
 	}
 	if err := c.Validate(request); err != nil {
-		r.logger.Error("Validation Error: Request payload does not follow proper format", zap.String("error", err.Error()))
-		return errors.NewRapidLinksError(err.Error(), 400)
+		logger.Error("Validation Error: Request payload does not follow proper format", zap.String("error", err.Error()))
+		return errors.NewCodedError(errors.CodeInvalidRequest, err.Error())
 	}
 
-	response, err := r.RapidResourceService.HandleResource(c, request)
-	if err != nil {
-		r.logger.Error("Failed to handle resource", zap.String("error", err.Error()))
-		return errors.NewRapidLinksError(err.Error(), 500)
+	// the query string is only ever forwarded inside the encrypted payload
+	request.Query = nil
+	if queryParams := c.QueryParams(); len(queryParams) > 0 {
+		request.Query = queryParams
 	}
 
-	var data map[string]interface{}
-	err = json.Unmarshal([]byte(response.Message), &data)
+	response, err := r.RapidResourceService.HandleResource(c, route, request)
 	if err != nil {
-		r.logger.Error("Failed to unmarshal request", zap.String("error", err.Error()))
+		logger.Error("Failed to handle resource", zap.String("error", err.Error()))
 		return err
 	}
 
-	if err := c.JSON(200, data); err != nil {
-		r.logger.Error("Failed to send response", zap.String("error", err.Error()))
-		return errors.NewRapidLinksError(err.Error(), 500)
+	if !json.Valid([]byte(response.Message)) {
+		logger.Error("Failed to unmarshal response", zap.Int("status_code", response.StatusCode))
+		return errors.NewCodedError(errors.CodeUpstreamInvalidResponse, "invalid response payload from rapid links")
+	}
+
+	if err := c.JSONBlob(response.StatusCode, []byte(response.Message)); err != nil {
+		logger.Error("Failed to send response", zap.String("error", err.Error()))
+		return err
 	}
 	return nil
 }
 
-func NewRapidResourceHandler(logger port.Logger, service *service.RapidResourceService) *resourceHandler {
+func NewRapidResourceHandler(logger port.Logger, routes port.RouteTable, service *service.RapidResourceService) *resourceHandler {
 	return &resourceHandler{
 		logger:               logger,
+		routes:               routes,
 		RapidResourceService: service,
 	}
 }
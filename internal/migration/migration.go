@@ -0,0 +1,198 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/pkg/util"
+	"time"
+)
+
+// LegacySchemaVersion is assumed for data directories created before the
+// schema version file was introduced.
+const LegacySchemaVersion = 1
+
+type SchemaVersion struct {
+	Version    int       `json:"schema_version"`
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// Migration upgrades a data directory from one schema version to the next.
+// Steps work on the raw JSON files so that they keep working when the config
+// structs change later on.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(dataDir string) error
+}
+
+type Migrator struct {
+	dataDir    string
+	migrations []Migration
+}
+
+func NewMigrator(dataDir string) *Migrator {
+	return &Migrator{
+		dataDir:    dataDir,
+		migrations: migrations,
+	}
+}
+
+func ReadSchemaVersion(dataDir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, constants.SchemaVersionFile))
+	if os.IsNotExist(err) {
+		return LegacySchemaVersion, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	var schemaVersion SchemaVersion
+	if err := json.Unmarshal(data, &schemaVersion); err != nil {
+		return 0, fmt.Errorf("failed to decode schema version: %w", err)
+	}
+
+	return schemaVersion.Version, nil
+}
+
+func WriteSchemaVersion(dataDir string, version int) error {
+	data, err := json.MarshalIndent(SchemaVersion{Version: version, MigratedAt: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema version: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dataDir, constants.SchemaVersionFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write schema version: %w", err)
+	}
+
+	return nil
+}
+
+// CheckSchemaVersion returns an error when the data directory needs to be
+// migrated, or was written by a newer build.
+func CheckSchemaVersion(dataDir string) error {
+	version, err := ReadSchemaVersion(dataDir)
+	if err != nil {
+		return err
+	}
+
+	if version < constants.DataSchemaVersion {
+		return fmt.Errorf("data directory %s has schema version %d but %d is required, run `rapid-bridge migrate`", dataDir, version, constants.DataSchemaVersion)
+	}
+	if version > constants.DataSchemaVersion {
+		return fmt.Errorf("data directory %s has schema version %d which is newer than the supported version %d", dataDir, version, constants.DataSchemaVersion)
+	}
+
+	return nil
+}
+
+// Plan returns the current schema version and the steps needed to reach the
+// version supported by this build.
+func (m *Migrator) Plan() (int, []Migration, error) {
+	if _, err := os.Stat(filepath.Join(m.dataDir, constants.CoreConfigFile)); err != nil {
+		return 0, nil, fmt.Errorf("no data directory found at %s: %w", m.dataDir, err)
+	}
+
+	current, err := ReadSchemaVersion(m.dataDir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if current > constants.DataSchemaVersion {
+		return current, nil, fmt.Errorf("schema version %d is newer than the supported version %d", current, constants.DataSchemaVersion)
+	}
+
+	steps := []Migration{}
+	for version := current; version < constants.DataSchemaVersion; version++ {
+		step, ok := m.find(version)
+		if !ok {
+			return current, nil, fmt.Errorf("no migration registered from schema version %d", version)
+		}
+		steps = append(steps, step)
+	}
+
+	return current, steps, nil
+}
+
+// Run backs up the data directory and applies every pending step. The
+// schema version is written after each step, so a failed run can be resumed.
+// It returns the backup location, or an empty string when nothing was done.
+func (m *Migrator) Run() (string, error) {
+	_, steps, err := m.Plan()
+	if err != nil {
+		return "", err
+	}
+
+	if len(steps) == 0 {
+		return "", nil
+	}
+
+	backupDir, err := m.Backup()
+	if err != nil {
+		return "", err
+	}
+
+	for _, step := range steps {
+		if err := step.Apply(m.dataDir); err != nil {
+			return backupDir, fmt.Errorf("migration %d -> %d failed: %w", step.From, step.From+1, err)
+		}
+
+		if err := WriteSchemaVersion(m.dataDir, step.From+1); err != nil {
+			return backupDir, err
+		}
+	}
+
+	return backupDir, nil
+}
+
+// Backup copies the data directory next to itself with a timestamp suffix.
+func (m *Migrator) Backup() (string, error) {
+	dataDir := filepath.Clean(m.dataDir)
+	backupDir := fmt.Sprintf("%s.backup-%s", dataDir, time.Now().UTC().Format("20060102T150405Z"))
+
+	if err := util.CopyDir(dataDir, backupDir); err != nil {
+		return "", fmt.Errorf("failed to back up data directory: %w", err)
+	}
+
+	return backupDir, nil
+}
+
+func (m *Migrator) find(from int) (Migration, bool) {
+	for _, step := range m.migrations {
+		if step.From == from {
+			return step, true
+		}
+	}
+	return Migration{}, false
+}
+
+var errNotObject = errors.New("expected a JSON object")
+
+func readJSONObject(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if object == nil {
+		return nil, fmt.Errorf("%s: %w", path, errNotObject)
+	}
+
+	return object, nil
+}
+
+func writeJSONObject(path string, object map[string]any) error {
+	data, err := json.MarshalIndent(object, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
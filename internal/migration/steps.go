@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+)
+
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "Give every registered bank its own rapid_urls, seeded from the global rapid_links_url",
+		Apply:       addBankRapidUrls,
+	},
+	{
+		From:        2,
+		Description: "Add the disabled_applications list to core.json",
+		Apply:       addDisabledApplications,
+	},
+}
+
+func addBankRapidUrls(dataDir string) error {
+	core, err := readJSONObject(filepath.Join(dataDir, constants.CoreConfigFile))
+	if err != nil {
+		return err
+	}
+
+	rapidLinksUrl, _ := core["rapid_links_url"].(string)
+	registeredBanks, _ := core["registered_banks"].([]any)
+
+	for _, registeredBank := range registeredBanks {
+		bankSlug, ok := registeredBank.(string)
+		if !ok || bankSlug == "" {
+			continue
+		}
+
+		bankConfigPath := filepath.Join(dataDir, constants.Bank, bankSlug, bankSlug+".json")
+
+		bankDetails, err := readJSONObject(bankConfigPath)
+		if os.IsNotExist(err) {
+			bankDetails = map[string]any{"slug": bankSlug}
+		} else if err != nil {
+			return err
+		}
+
+		if rapidUrls, _ := bankDetails["rapid_urls"].([]any); len(rapidUrls) > 0 {
+			continue
+		}
+
+		rapidUrls := []any{}
+		if rapidLinksUrl != "" {
+			rapidUrls = append(rapidUrls, rapidLinksUrl)
+		}
+		bankDetails["rapid_urls"] = rapidUrls
+
+		if err := os.MkdirAll(filepath.Dir(bankConfigPath), 0755); err != nil {
+			return err
+		}
+		if err := writeJSONObject(bankConfigPath, bankDetails); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addDisabledApplications(dataDir string) error {
+	coreConfigPath := filepath.Join(dataDir, constants.CoreConfigFile)
+
+	core, err := readJSONObject(coreConfigPath)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := core["disabled_applications"]; ok {
+		return nil
+	}
+	core["disabled_applications"] = []any{}
+
+	return writeJSONObject(coreConfigPath, core)
+}
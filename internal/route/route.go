@@ -1,29 +1,78 @@
 package route
 
 import (
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
 	"rapid-bridge/domain/security"
+	"rapid-bridge/internal/adapter/credentials"
+	"rapid-bridge/internal/adapter/idempotency"
 	keymanagementfs "rapid-bridge/internal/adapter/keymanagement_fs"
+	"rapid-bridge/internal/adapter/ratelimit"
 	securityadapter "rapid-bridge/internal/adapter/security"
+	"rapid-bridge/internal/adapter/upstream"
 	"rapid-bridge/internal/handler"
 	"rapid-bridge/internal/service"
 	"rapid-bridge/internal/setup"
+	"rapid-bridge/pkg/metrics"
 	"rapid-bridge/pkg/middleware"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
-func SetupRoutes(e *echo.Echo, app *setup.Application) {
+// Authentication modes for the resource routes.
+const (
+	AuthAPIKey = "api-key"
+	AuthMTLS   = "mtls"
+	AuthNone   = "none"
+)
+
+type Options struct {
+	Auth string
+	// maps client certificates to applications, required with AuthMTLS
+	ClientIdentities port.ClientIdentityResolver
+	// how long responses to requests with an Idempotency-Key are kept, 0 to
+	// ignore the header
+	IdempotencyWindow time.Duration
+}
+
+// SetupRoutes registers the routes of the bridge. The returned function
+// stops the background work they started, once the server has shut down.
+func SetupRoutes(e *echo.Echo, app *setup.Application, options Options) func() {
+	var stops []func()
 
 	swaggerRoutes(e)
 
+	metrics.Default.MustRegister(service.NewMetricsService(app.Config))
+
 	api := e.Group("/api/v1")
 
-	rapidResource := api.Group("/resource", middleware.APIContractMiddleware())
-	resourceForwardingRoutes(rapidResource, app)
+	resourceMiddlewares := []echo.MiddlewareFunc{middleware.MetricsMiddleware(app.Routes, app.Config)}
+	// before the contract check, which needs the X-Source-Slug it fills in
+	if options.Auth == AuthMTLS {
+		resourceMiddlewares = append(resourceMiddlewares, middleware.ClientCertificateMiddleware(options.ClientIdentities, app.Logger))
+	}
+	resourceMiddlewares = append(resourceMiddlewares, middleware.APIContractMiddleware())
+	if options.Auth != AuthMTLS && options.Auth != AuthNone {
+		credentialService := service.NewCredentialService(credentials.NewFSCredentialStore(app.Config.GetDataDir()), app.Logger)
+		resourceMiddlewares = append(resourceMiddlewares, middleware.APIKeyMiddleware(credentialService, app.Logger))
+	}
+	// after authentication, so that requests count against the application
+	// that really sent them, and before anything else is done for them
+	resourceMiddlewares = append(resourceMiddlewares, middleware.RateLimitMiddleware(ratelimit.NewLimiters(app.Limits), app.Routes, app.Config, app.Logger))
+	// after authentication, since keys are scoped to the source application
+	if options.IdempotencyWindow > 0 {
+		idempotencyStore := idempotency.NewFSIdempotencyStore(app.Config.GetDataDir())
+		stops = append(stops, idempotencyStore.StartPurging(constants.IdempotencyPurgeInterval*time.Minute, app.Logger))
+		resourceMiddlewares = append(resourceMiddlewares, middleware.IdempotencyMiddleware(idempotencyStore, options.IdempotencyWindow, app.Logger))
+	}
+
+	rapidResource := api.Group("/resource", resourceMiddlewares...)
+	stops = append(stops, resourceForwardingRoutes(e, rapidResource, app))
 
 	// Route to register new application in bridge
 	// This is just for playground and not for production
-	cliApp := setup.NewCLIApplication()
+	cliApp := setup.NewCLIApplication(app.Config.GetDataDir())
 	keyLoader := keymanagementfs.NewFSKeyLoader()
 	keyConverter := keymanagementfs.NewFSKeyConverter()
 	keySaver := keymanagementfs.NewFSKeySaver()
@@ -32,21 +81,34 @@ func SetupRoutes(e *echo.Echo, app *setup.Application) {
 	playgroundHandler := handler.NewPlaygroundHandler(cliApp.Logger, playgroundService)
 	api.POST("/application/register", playgroundHandler.HandleApplicationRegister)
 
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
 }
 
-func resourceForwardingRoutes(resourceRoutes *echo.Group, app *setup.Application) {
+func resourceForwardingRoutes(e *echo.Echo, resourceRoutes *echo.Group, app *setup.Application) func() {
 
 	newCipher := securityadapter.NewHybridCryptography()
 	newSecurity := security.NewSecurity(newCipher)
 
 	keyLoader := keymanagementfs.NewFSKeyLoader()
 
-	service := service.NewRapidResourceService(keyLoader, *newSecurity, app.Logger, app.Config)
-	handler := handler.NewRapidResourceHandler(app.Logger, service)
+	upstreams := upstream.NewEndpointPool(app.Logger, constants.UpstreamProbeTimeout*time.Second)
+	stopHealthChecks := upstreams.StartHealthChecks(constants.UpstreamHealthCheckInterval * time.Second)
+
+	breakers := upstream.NewCircuitBreakers(app.Logger, constants.CircuitBreakerThreshold, constants.CircuitBreakerCooldown*time.Second)
+
+	healthHandler := handler.NewHealthHandler(service.NewHealthService(app.Config, upstreams, breakers))
+	e.GET("/health", healthHandler.HandleHealth)
+
+	service := service.NewRapidResourceService(keyLoader, *newSecurity, upstreams, upstream.NewClientCache(), breakers, app.Logger, app.Config)
+	handler := handler.NewRapidResourceHandler(app.Logger, app.Routes, service)
+
+	// the route table decides which paths and methods exist, so that it can
+	// change without restarting the server
+	resourceRoutes.Any("/*", handler.HandleResource)
 
-	resourceRoutes.POST("/balance", handler.HandleResource)
-	resourceRoutes.POST("/statement", handler.HandleResource)
-	resourceRoutes.POST("/payment/initiate", handler.HandleResource)
-	resourceRoutes.POST("/payment/approve", handler.HandleResource)
-	resourceRoutes.POST("/account/open", handler.HandleResource)
+	return stopHealthChecks
 }
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/keys"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/migration"
+	"rapid-bridge/pkg/security/age"
+	"rapid-bridge/pkg/util"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrRegistryExists = stderrors.New("the data directory already holds a registry")
+var ErrBackupCorrupted = stderrors.New("backup failed the integrity check")
+var ErrBackupKeysInvalid = stderrors.New("backup contains invalid keys")
+
+// BackupFormatVersion is bumped when the archive layout changes.
+const BackupFormatVersion = 1
+
+// the manifest is the first entry of the archive and lists every other one
+const backupManifestFile = "MANIFEST.json"
+
+type BackupFile struct {
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+type BackupManifest struct {
+	FormatVersion int          `json:"format_version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	SchemaVersion int          `json:"schema_version"`
+	Applications  []string     `json:"applications"`
+	Banks         []string     `json:"banks"`
+	Files         []BackupFile `json:"files"`
+}
+
+type RestoreResult struct {
+	Manifest BackupManifest `json:"manifest"`
+	DataDir  string         `json:"data_dir"`
+	// where the registry that was replaced with --force was moved to
+	PreviousDataDir string `json:"previous_data_dir,omitempty"`
+	DryRun          bool   `json:"dry_run"`
+}
+
+type backupCoreConfig struct {
+	RegisteredApplications []string `json:"registered_applications"`
+	RegisteredBanks        []string `json:"registered_banks"`
+}
+
+// BackupService snapshots a data directory into a single passphrase
+// encrypted archive (a tar file inside the age format) and restores it. Like
+// DoctorService it works on the files directly, so a registry that would not
+// load can still be backed up.
+type BackupService struct {
+	dataDir    string
+	workFactor int
+	logger     port.Logger
+}
+
+func NewBackupService(dataDir string, workFactor int, logger port.Logger) *BackupService {
+	return &BackupService{
+		// the staging and previous directories are named after it
+		dataDir:    filepath.Clean(dataDir),
+		workFactor: workFactor,
+		logger:     logger,
+	}
+}
+
+// Create archives core.json, the schema version, every application and bank
+// config and all key versions, and encrypts the archive with the passphrase.
+func (b *BackupService) Create(passphrase string) ([]byte, BackupManifest, error) {
+	core, err := readBackupCoreConfig(filepath.Join(b.dataDir, constants.CoreConfigFile))
+	if err != nil {
+		return nil, BackupManifest{}, err
+	}
+
+	schemaVersion, err := migration.ReadSchemaVersion(b.dataDir)
+	if err != nil {
+		return nil, BackupManifest{}, err
+	}
+
+	manifest := BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		SchemaVersion: schemaVersion,
+		Applications:  core.RegisteredApplications,
+		Banks:         core.RegisteredBanks,
+		Files:         []BackupFile{},
+	}
+	contents := map[string][]byte{}
+
+	err = filepath.WalkDir(b.dataDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// stored responses are only worth keeping for the idempotency window
+		if entry.IsDir() && filePath == filepath.Join(b.dataDir, constants.IdempotencyDir) {
+			return filepath.SkipDir
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(b.dataDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		archivePath := filepath.ToSlash(relativePath)
+		manifest.Files = append(manifest.Files, BackupFile{
+			Path:   archivePath,
+			Size:   int64(len(data)),
+			Mode:   info.Mode().Perm(),
+			SHA256: sha256Hex(data),
+		})
+		contents[archivePath] = data
+		return nil
+	})
+	if err != nil {
+		return nil, BackupManifest{}, fmt.Errorf("failed to read the data directory: %w", err)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, BackupManifest{}, err
+	}
+
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	if err := writeTarFile(writer, backupManifestFile, 0644, manifest.CreatedAt, manifestData); err != nil {
+		return nil, BackupManifest{}, err
+	}
+	for _, file := range manifest.Files {
+		if err := writeTarFile(writer, file.Path, file.Mode, manifest.CreatedAt, contents[file.Path]); err != nil {
+			return nil, BackupManifest{}, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, BackupManifest{}, err
+	}
+
+	bundle, err := age.Encrypt(archive.Bytes(), passphrase, b.workFactor)
+	if err != nil {
+		return nil, BackupManifest{}, fmt.Errorf("failed to encrypt the backup: %w", err)
+	}
+
+	b.logger.Info("Backup created", zap.String("data_dir", b.dataDir), zap.Int("files", len(manifest.Files)))
+	return bundle, manifest, nil
+}
+
+// Restore decrypts the bundle, checks every file against the manifest and
+// validates the keys before anything in the data directory is touched. The
+// files are unpacked next to the data directory and swapped in with a
+// rename; an existing registry is only replaced when force is set, and is
+// kept next to it rather than deleted.
+func (b *BackupService) Restore(bundle []byte, passphrase string, force, dryRun bool) (RestoreResult, error) {
+	result := RestoreResult{DataDir: b.dataDir, DryRun: dryRun}
+
+	manifest, contents, err := openBackup(bundle, passphrase)
+	if err != nil {
+		return result, err
+	}
+	result.Manifest = manifest
+
+	if manifest.SchemaVersion > constants.DataSchemaVersion {
+		return result, fmt.Errorf("backup has schema version %d, newer than %d supported by this build", manifest.SchemaVersion, constants.DataSchemaVersion)
+	}
+
+	exists, err := registryExists(b.dataDir)
+	if err != nil {
+		return result, err
+	}
+	if exists && !force && !dryRun {
+		return result, fmt.Errorf("%w: %s, pass --force to replace it", ErrRegistryExists, b.dataDir)
+	}
+
+	suffix := time.Now().UTC().Format("20060102T150405Z")
+	stagingDir := b.dataDir + ".restore-" + suffix
+	if err := unpackBackup(stagingDir, manifest, contents); err != nil {
+		os.RemoveAll(stagingDir)
+		return result, fmt.Errorf("failed to unpack the backup: %w", err)
+	}
+
+	if err := validateRestoredKeys(stagingDir); err != nil {
+		os.RemoveAll(stagingDir)
+		return result, err
+	}
+
+	if dryRun {
+		return result, os.RemoveAll(stagingDir)
+	}
+
+	if exists {
+		result.PreviousDataDir = b.dataDir + ".pre-restore-" + suffix
+		if err := os.Rename(b.dataDir, result.PreviousDataDir); err != nil {
+			os.RemoveAll(stagingDir)
+			return result, fmt.Errorf("failed to move the existing data directory aside: %w", err)
+		}
+	} else if err := os.RemoveAll(b.dataDir); err != nil {
+		// only an empty skeleton left by a previous init
+		os.RemoveAll(stagingDir)
+		return result, err
+	}
+
+	if err := os.Rename(stagingDir, b.dataDir); err != nil {
+		return result, fmt.Errorf("failed to move the restored data directory into place, it is left at %s: %w", stagingDir, err)
+	}
+
+	b.logger.Info("Backup restored", zap.String("data_dir", b.dataDir), zap.Int("files", len(manifest.Files)), zap.String("previous_data_dir", result.PreviousDataDir))
+	return result, nil
+}
+
+func openBackup(bundle []byte, passphrase string) (BackupManifest, map[string][]byte, error) {
+	manifest := BackupManifest{}
+
+	archive, err := age.Decrypt(bundle, passphrase)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("%w: %w", ErrBackupCorrupted, err)
+	}
+
+	reader := tar.NewReader(bytes.NewReader(archive))
+	contents := map[string][]byte{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%w: %w", ErrBackupCorrupted, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return manifest, nil, fmt.Errorf("%w: unexpected entry %s", ErrBackupCorrupted, header.Name)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%w: %w", ErrBackupCorrupted, err)
+		}
+		contents[header.Name] = data
+	}
+
+	manifestData, ok := contents[backupManifestFile]
+	if !ok {
+		return manifest, nil, fmt.Errorf("%w: %s is missing", ErrBackupCorrupted, backupManifestFile)
+	}
+	delete(contents, backupManifestFile)
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("%w: invalid manifest: %w", ErrBackupCorrupted, err)
+	}
+	if manifest.FormatVersion != BackupFormatVersion {
+		return manifest, nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+
+	if len(manifest.Files) != len(contents) {
+		return manifest, nil, fmt.Errorf("%w: manifest lists %d files, archive holds %d", ErrBackupCorrupted, len(manifest.Files), len(contents))
+	}
+	for _, file := range manifest.Files {
+		// entries must stay inside the data directory
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) || path.Clean(file.Path) != file.Path {
+			return manifest, nil, fmt.Errorf("%w: unsafe path %s", ErrBackupCorrupted, file.Path)
+		}
+		data, ok := contents[file.Path]
+		if !ok {
+			return manifest, nil, fmt.Errorf("%w: %s is missing", ErrBackupCorrupted, file.Path)
+		}
+		if int64(len(data)) != file.Size || sha256Hex(data) != file.SHA256 {
+			return manifest, nil, fmt.Errorf("%w: checksum mismatch for %s", ErrBackupCorrupted, file.Path)
+		}
+	}
+
+	return manifest, contents, nil
+}
+
+func unpackBackup(dir string, manifest BackupManifest, contents map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, subdir := range []string{constants.Application, constants.Bank} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0755); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range manifest.Files {
+		target := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, contents[file.Path], file.Mode.Perm()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRestoredKeys parses every key in the restored directory, and checks
+// that each registered application has a complete, matching current key
+// version and each bank its public keys.
+func validateRestoredKeys(dir string) error {
+	core, err := readBackupCoreConfig(filepath.Join(dir, constants.CoreConfigFile))
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".pem" {
+			return err
+		}
+		if _, err := keys.ReadAndValidateKeyFile(filePath, strings.Contains(entry.Name(), "private")); err != nil {
+			relativePath, _ := filepath.Rel(dir, filePath)
+			return fmt.Errorf("%w: %s: %w", ErrBackupKeysInvalid, relativePath, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, applicationSlug := range core.RegisteredApplications {
+		applicationDetails := port.CLIApplicationDetails{}
+		configPath := filepath.Join(dir, constants.Application, applicationSlug, applicationSlug+".json")
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return fmt.Errorf("%w: application %s has no config", ErrBackupKeysInvalid, applicationSlug)
+		}
+		if err := json.Unmarshal(data, &applicationDetails); err != nil {
+			return fmt.Errorf("%w: application %s has an invalid config: %w", ErrBackupKeysInvalid, applicationSlug, err)
+		}
+
+		keyVersion := applicationDetails.KeyVersion
+		pairs := [][2]string{
+			{util.GetRSAPrivateKeyPath(dir, applicationSlug, keyVersion), util.GetRSAPublicKeyPath(dir, applicationSlug, keyVersion)},
+			{util.GetEd25519PrivateKeyPath(dir, applicationSlug, keyVersion), util.GetEd25519PublicKeyPath(dir, applicationSlug, keyVersion)},
+		}
+		for _, pair := range pairs {
+			privateKey, err := keys.ReadAndValidateKeyFile(pair[0], true)
+			if err != nil {
+				return fmt.Errorf("%w: application %s key version %s: %w", ErrBackupKeysInvalid, applicationSlug, keyVersion, err)
+			}
+			publicKey, err := keys.ReadAndValidateKeyFile(pair[1], false)
+			if err != nil {
+				return fmt.Errorf("%w: application %s key version %s: %w", ErrBackupKeysInvalid, applicationSlug, keyVersion, err)
+			}
+			if !keys.PairMatches(privateKey, publicKey) {
+				return fmt.Errorf("%w: application %s key version %s: %s does not match its private key", ErrBackupKeysInvalid, applicationSlug, keyVersion, filepath.Base(pair[1]))
+			}
+		}
+	}
+
+	for _, bankSlug := range core.RegisteredBanks {
+		for _, keyPath := range []string{util.GetBankRSAPublicKeyPath(dir, bankSlug), util.GetBankEd25519PublicKeyPath(dir, bankSlug)} {
+			if _, err := keys.ReadAndValidateKeyFile(keyPath, false); err != nil {
+				return fmt.Errorf("%w: bank %s: %w", ErrBackupKeysInvalid, bankSlug, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// registryExists reports whether dir holds anything worth protecting: a
+// core.json with registrations, or any other file.
+func registryExists(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			subEntries, err := os.ReadDir(filepath.Join(dir, entry.Name()))
+			if err != nil || len(subEntries) > 0 {
+				return true, nil
+			}
+			continue
+		}
+		if entry.Name() == constants.SchemaVersionFile {
+			continue
+		}
+		if entry.Name() == constants.CoreConfigFile {
+			core, err := readBackupCoreConfig(filepath.Join(dir, entry.Name()))
+			if err != nil || len(core.RegisteredApplications) > 0 || len(core.RegisteredBanks) > 0 {
+				return true, nil
+			}
+			continue
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func readBackupCoreConfig(corePath string) (backupCoreConfig, error) {
+	core := backupCoreConfig{}
+
+	data, err := os.ReadFile(corePath)
+	if err != nil {
+		return core, fmt.Errorf("failed to read %s: %w", constants.CoreConfigFile, err)
+	}
+	if err := json.Unmarshal(data, &core); err != nil {
+		return core, fmt.Errorf("invalid %s: %w", constants.CoreConfigFile, err)
+	}
+
+	slices.Sort(core.RegisteredApplications)
+	slices.Sort(core.RegisteredBanks)
+	return core, nil
+}
+
+func writeTarFile(writer *tar.Writer, name string, mode fs.FileMode, modTime time.Time, data []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	}
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := writer.Write(data)
+	return err
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"rapid-bridge/domain/port"
+	errors "rapid-bridge/internal/error"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrUnauthenticated = stderrors.New("invalid api key")
+var ErrAPIKeyNotFound = stderrors.New("api key not found")
+
+// api keys look like rbk_<id>_<secret>; the id finds the stored hash without
+// revealing anything about the secret
+const apiKeyPrefix = "rbk_"
+
+// IssuedAPIKey is a newly created key. Key is the only copy of the secret.
+type IssuedAPIKey struct {
+	port.APIKey
+	Key string `json:"key"`
+}
+
+// CredentialService issues the API keys applications authenticate with, and
+// checks them on the server.
+type CredentialService struct {
+	store  port.CredentialStore
+	logger port.Logger
+}
+
+func NewCredentialService(store port.CredentialStore, logger port.Logger) *CredentialService {
+	return &CredentialService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// IssueAPIKey creates a key for the application. validFor of zero means the
+// key does not expire.
+func (c *CredentialService) IssueAPIKey(applicationSlug, name string, validFor time.Duration) (IssuedAPIKey, error) {
+	apiKeys, err := c.store.LoadAPIKeys(applicationSlug)
+	if err != nil {
+		return IssuedAPIKey{}, err
+	}
+
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return IssuedAPIKey{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return IssuedAPIKey{}, err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	apiKey := port.APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Hash:      hashAPIKeySecret(encodedSecret),
+		CreatedAt: time.Now().UTC(),
+	}
+	if validFor > 0 {
+		expiresAt := apiKey.CreatedAt.Add(validFor)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := c.store.SaveAPIKeys(applicationSlug, append(apiKeys, apiKey)); err != nil {
+		return IssuedAPIKey{}, err
+	}
+
+	c.logger.Info("API key issued", zap.String("slug", applicationSlug), zap.String("key_id", apiKey.ID))
+
+	return IssuedAPIKey{
+		APIKey: apiKey,
+		Key:    apiKeyPrefix + apiKey.ID + "_" + encodedSecret,
+	}, nil
+}
+
+func (c *CredentialService) ListAPIKeys(applicationSlug string) ([]port.APIKey, error) {
+	return c.store.LoadAPIKeys(applicationSlug)
+}
+
+// RevokeAPIKey marks a key revoked. It is kept in the file so that the id is
+// not reused and the revocation stays visible.
+func (c *CredentialService) RevokeAPIKey(applicationSlug, id string) error {
+	apiKeys, err := c.store.LoadAPIKeys(applicationSlug)
+	if err != nil {
+		return err
+	}
+
+	for i := range apiKeys {
+		if apiKeys[i].ID != id {
+			continue
+		}
+		if apiKeys[i].RevokedAt != nil {
+			return nil
+		}
+
+		revokedAt := time.Now().UTC()
+		updated := append([]port.APIKey{}, apiKeys...)
+		updated[i].RevokedAt = &revokedAt
+		if err := c.store.SaveAPIKeys(applicationSlug, updated); err != nil {
+			return err
+		}
+
+		c.logger.Info("API key revoked", zap.String("slug", applicationSlug), zap.String("key_id", id))
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s for application %s", ErrAPIKeyNotFound, id, applicationSlug)
+}
+
+// AuthenticateAPIKey checks that apiKey is a valid key of the application.
+// Every failure wraps ErrUnauthenticated; the reason is only meant for logs.
+func (c *CredentialService) AuthenticateAPIKey(applicationSlug, apiKey string) error {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(apiKey, apiKeyPrefix), "_")
+	if !strings.HasPrefix(apiKey, apiKeyPrefix) || !ok || id == "" || secret == "" {
+		return fmt.Errorf("%w: malformed", ErrUnauthenticated)
+	}
+
+	apiKeys, err := c.store.LoadAPIKeys(applicationSlug)
+	if err != nil {
+		return err
+	}
+
+	for _, stored := range apiKeys {
+		if stored.ID != id {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hashAPIKeySecret(secret))) != 1 {
+			return fmt.Errorf("%w: wrong secret for key %s", ErrUnauthenticated, id)
+		}
+		if stored.RevokedAt != nil {
+			return fmt.Errorf("%w: key %s is revoked", ErrUnauthenticated, id)
+		}
+		if stored.ExpiresAt != nil && time.Now().After(*stored.ExpiresAt) {
+			return fmt.Errorf("%w: key %s: %w", ErrUnauthenticated, id, errors.NewCodedError(errors.CodeKeyExpired, "api key expired"))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: key %s is not a key of %s", ErrUnauthenticated, id, applicationSlug)
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
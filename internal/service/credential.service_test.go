@@ -0,0 +1,75 @@
+package service
+
+import (
+	stderrors "errors"
+	"rapid-bridge/internal/adapter/credentials"
+	"rapid-bridge/internal/adapter/logger"
+	errors "rapid-bridge/internal/error"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialService := NewCredentialService(credentials.NewFSCredentialStore(t.TempDir()), zapLogger)
+
+	issue := func(applicationSlug string, validFor time.Duration) IssuedAPIKey {
+		t.Helper()
+		issued, err := credentialService.IssueAPIKey(applicationSlug, "test", validFor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return issued
+	}
+	valid := issue("app", 0)
+	expiring := issue("app", time.Hour)
+	expired := issue("app", time.Nanosecond)
+	revoked := issue("app", 0)
+	if err := credentialService.RevokeAPIKey("app", revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	other := issue("other-app", 0)
+
+	id, secret, _ := strings.Cut(strings.TrimPrefix(valid.Key, apiKeyPrefix), "_")
+
+	tests := []struct {
+		name            string
+		applicationSlug string
+		apiKey          string
+		wantErr         bool
+		wantCode        errors.Code
+	}{
+		{"valid", "app", valid.Key, false, ""},
+		{"valid with expiry", "app", expiring.Key, false, ""},
+		{"expired", "app", expired.Key, true, errors.CodeKeyExpired},
+		{"revoked", "app", revoked.Key, true, ""},
+		{"key of another application", "app", other.Key, true, ""},
+		{"unknown application", "missing-app", valid.Key, true, ""},
+		{"wrong secret", "app", apiKeyPrefix + id + "_" + strings.Repeat("A", len(secret)), true, ""},
+		{"missing prefix", "app", strings.TrimPrefix(valid.Key, apiKeyPrefix), true, ""},
+		{"missing secret", "app", apiKeyPrefix + id + "_", true, ""},
+		{"missing id", "app", apiKeyPrefix + "_" + secret, true, ""},
+		{"empty", "app", "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := credentialService.AuthenticateAPIKey(tt.applicationSlug, tt.apiKey)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("AuthenticateAPIKey = %v, want nil", err)
+				}
+				return
+			}
+			if !stderrors.Is(err, ErrUnauthenticated) {
+				t.Fatalf("AuthenticateAPIKey = %v, want ErrUnauthenticated", err)
+			}
+			if code, _ := errors.CodeOf(err); code != tt.wantCode {
+				t.Fatalf("code = %s, want %s", code, tt.wantCode)
+			}
+		})
+	}
+}
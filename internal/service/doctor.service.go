@@ -0,0 +1,427 @@
+package service
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/keys"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/adapter/credentials"
+	"rapid-bridge/internal/adapter/upstream"
+	"rapid-bridge/internal/migration"
+	"rapid-bridge/pkg/config"
+	"rapid-bridge/pkg/security/tlsconfig"
+	"rapid-bridge/pkg/util"
+	"slices"
+	"strings"
+	"time"
+)
+
+type DoctorStatus string
+
+const (
+	DoctorOK   DoctorStatus = "ok"
+	DoctorWarn DoctorStatus = "warn"
+	DoctorFail DoctorStatus = "fail"
+)
+
+// keys expiring within this many days are reported as a warning
+const doctorExpiryWarningDays = 14
+
+type DoctorCheck struct {
+	Subject string       `json:"subject"`
+	Status  DoctorStatus `json:"status"`
+	Detail  string       `json:"detail"`
+	Hint    string       `json:"hint,omitempty"`
+}
+
+type DoctorReport struct {
+	DataDir  string        `json:"data_dir"`
+	Checks   []DoctorCheck `json:"checks"`
+	Warnings int           `json:"warnings"`
+	Failures int           `json:"failures"`
+}
+
+type doctorCoreConfig struct {
+	RapidLinksURL          string   `json:"rapid_links_url"`
+	RegisteredApplications []string `json:"registered_applications"`
+	DisabledApplications   []string `json:"disabled_applications"`
+	RegisteredBanks        []string `json:"registered_banks"`
+}
+
+// DoctorService inspects an install for the usual misconfigurations. It reads
+// the data directory directly instead of going through the config adapters,
+// so that it still reports something useful when they would refuse to load.
+type DoctorService struct {
+	dataDir string
+	// the env file serve would read, and whether it was given explicitly
+	configFile     string
+	configRequired bool
+	probeTimeout   time.Duration
+	skipNetwork    bool
+
+	report DoctorReport
+}
+
+func NewDoctorService(dataDir, configFile string, configRequired bool, probeTimeout time.Duration, skipNetwork bool) *DoctorService {
+	return &DoctorService{
+		dataDir:        dataDir,
+		configFile:     configFile,
+		configRequired: configRequired,
+		probeTimeout:   probeTimeout,
+		skipNetwork:    skipNetwork,
+	}
+}
+
+func (d *DoctorService) Run() DoctorReport {
+	d.report = DoctorReport{DataDir: d.dataDir, Checks: []DoctorCheck{}}
+
+	d.checkEnvFile()
+
+	if !d.checkLayout() {
+		return d.report
+	}
+
+	core, ok := d.checkCoreConfig()
+	if !ok {
+		return d.report
+	}
+
+	for _, applicationSlug := range core.RegisteredApplications {
+		d.checkApplication(applicationSlug)
+	}
+	d.checkOrphans(constants.Application, core.RegisteredApplications)
+	for _, applicationSlug := range core.DisabledApplications {
+		if !slices.Contains(core.RegisteredApplications, applicationSlug) {
+			d.warn("application "+applicationSlug, "disabled but not registered", "remove it from disabled_applications in core.json")
+		}
+	}
+
+	for _, bankSlug := range core.RegisteredBanks {
+		d.checkBank(bankSlug, core.RapidLinksURL)
+	}
+	d.checkOrphans(constants.Bank, core.RegisteredBanks)
+
+	return d.report
+}
+
+// checkEnvFile resolves SERVER_PORT the way serve does: from the environment,
+// then from the env file, which may be left out unless it was asked for.
+func (d *DoctorService) checkEnvFile() {
+	if _, err := os.Stat(d.configFile); err != nil {
+		if d.configRequired {
+			d.fail("environment", d.configFile+" not found", "create it with SERVER_PORT set, or leave out --config to use the environment only")
+			return
+		}
+		d.ok("environment", "no "+d.configFile+" in the working directory, using the environment only")
+	}
+
+	cfg, err := config.LoadConfigFile(d.configFile, !d.configRequired)
+	if err != nil {
+		d.fail("environment", err.Error(), "fix the syntax of "+d.configFile+", one KEY=value per line")
+		return
+	}
+
+	if cfg.ServerPort == "" {
+		d.warn("environment", "SERVER_PORT is not set", "set it in the environment or in "+d.configFile+", e.g. SERVER_PORT=:8080, or start serve with --listen")
+		return
+	}
+
+	d.ok("environment", "SERVER_PORT is "+cfg.ServerPort)
+}
+
+func (d *DoctorService) checkLayout() bool {
+	info, err := os.Stat(d.dataDir)
+	if err != nil || !info.IsDir() {
+		d.fail("data directory", "not found: "+d.dataDir, "run any init command to create it, or point --data-dir at the right directory")
+		return false
+	}
+
+	layoutOK := true
+	for _, dir := range []string{constants.Application, constants.Bank} {
+		if info, err := os.Stat(filepath.Join(d.dataDir, dir)); err != nil || !info.IsDir() {
+			d.fail("data directory", dir+"/ directory is missing", "create "+filepath.Join(d.dataDir, dir))
+			layoutOK = false
+		}
+	}
+
+	version, err := migration.ReadSchemaVersion(d.dataDir)
+	switch {
+	case err != nil:
+		d.fail("data directory", "cannot read schema version: "+err.Error(), "")
+		layoutOK = false
+	case version < constants.DataSchemaVersion:
+		d.fail("data directory", fmt.Sprintf("schema version %d is older than %d", version, constants.DataSchemaVersion), "run rapid-bridge migrate")
+		layoutOK = false
+	case version > constants.DataSchemaVersion:
+		d.fail("data directory", fmt.Sprintf("schema version %d is newer than %d", version, constants.DataSchemaVersion), "upgrade rapid-bridge")
+		layoutOK = false
+	}
+
+	if layoutOK {
+		d.ok("data directory", fmt.Sprintf("%s, schema version %d", d.dataDir, version))
+	}
+	return layoutOK
+}
+
+func (d *DoctorService) checkCoreConfig() (doctorCoreConfig, bool) {
+	core := doctorCoreConfig{}
+
+	data, err := os.ReadFile(filepath.Join(d.dataDir, constants.CoreConfigFile))
+	if err != nil {
+		d.fail(constants.CoreConfigFile, "cannot read: "+err.Error(), "")
+		return core, false
+	}
+	if err := json.Unmarshal(data, &core); err != nil {
+		d.fail(constants.CoreConfigFile, "invalid JSON: "+err.Error(), "fix or restore "+filepath.Join(d.dataDir, constants.CoreConfigFile))
+		return core, false
+	}
+
+	// rapid_links_url is only needed by banks without their own rapid_urls
+	if banks := d.banksWithoutRapidUrls(core.RegisteredBanks); core.RapidLinksURL == "" && len(banks) > 0 {
+		d.warn(constants.CoreConfigFile, "rapid_links_url is empty but needed by the banks without rapid_urls: "+strings.Join(banks, ", "), "set it to the Rapid Links deployment, e.g. \"rapid_links_url\": \"http://localhost:9000/rapid-links\", or give the banks their own rapid_urls")
+	} else {
+		d.ok(constants.CoreConfigFile, fmt.Sprintf("%d applications, %d banks registered", len(core.RegisteredApplications), len(core.RegisteredBanks)))
+	}
+
+	return core, true
+}
+
+func (d *DoctorService) checkApplication(applicationSlug string) {
+	subject := "application " + applicationSlug
+
+	configPath := filepath.Join(d.dataDir, constants.Application, applicationSlug, applicationSlug+".json")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		d.fail(subject, "registered in core.json but its config is missing: "+err.Error(), "run rapid-bridge init app --slug "+applicationSlug+" --force, or rapid-bridge app remove "+applicationSlug)
+		return
+	}
+
+	applicationDetails := port.CLIApplicationDetails{}
+	if err := json.Unmarshal(data, &applicationDetails); err != nil {
+		d.fail(subject, "invalid config "+configPath+": "+err.Error(), "")
+		return
+	}
+	if applicationDetails.KeyVersion == "" {
+		d.fail(subject, "no current key version", "run rapid-bridge init app --slug "+applicationSlug+" --force")
+		return
+	}
+
+	keyVersion := applicationDetails.KeyVersion
+	rsaPrivateKey, rsaPrivateOK := d.checkKeyFile(subject, "RSA private key", util.GetRSAPrivateKeyPath(d.dataDir, applicationSlug, keyVersion), true)
+	rsaPublicKey, rsaPublicOK := d.checkKeyFile(subject, "RSA public key", util.GetRSAPublicKeyPath(d.dataDir, applicationSlug, keyVersion), false)
+	ed25519PrivateKey, ed25519PrivateOK := d.checkKeyFile(subject, "Ed25519 private key", util.GetEd25519PrivateKeyPath(d.dataDir, applicationSlug, keyVersion), true)
+	ed25519PublicKey, ed25519PublicOK := d.checkKeyFile(subject, "Ed25519 public key", util.GetEd25519PublicKeyPath(d.dataDir, applicationSlug, keyVersion), false)
+
+	if rsaPrivateOK && rsaPublicOK {
+		d.checkKeyPair(subject, "RSA", rsaPrivateKey, rsaPublicKey)
+	}
+	if ed25519PrivateOK && ed25519PublicOK {
+		d.checkKeyPair(subject, "Ed25519", ed25519PrivateKey, ed25519PublicKey)
+	}
+
+	d.checkExpiry(subject, "RSA keys", applicationDetails.RSAKeysValidUntil)
+	d.checkExpiry(subject, "Ed25519 keys", applicationDetails.Ed25519KeysValidUntil)
+
+	d.checkAPIKeys(subject, applicationSlug)
+}
+
+func (d *DoctorService) checkAPIKeys(subject, applicationSlug string) {
+	apiKeys, err := credentials.NewFSCredentialStore(d.dataDir).LoadAPIKeys(applicationSlug)
+	if err != nil {
+		d.fail(subject, "api keys: "+err.Error(), "")
+		return
+	}
+
+	active := 0
+	for _, apiKey := range apiKeys {
+		if apiKey.RevokedAt == nil && (apiKey.ExpiresAt == nil || time.Now().Before(*apiKey.ExpiresAt)) {
+			active++
+		}
+	}
+
+	if active == 0 {
+		d.warn(subject, "no active api key, the server rejects its requests", "issue one with rapid-bridge app key create "+applicationSlug)
+		return
+	}
+	d.ok(subject, fmt.Sprintf("%d active api keys", active))
+}
+
+// banksWithoutRapidUrls lists the banks whose config has no rapid_urls. Banks
+// whose config cannot be read are left to checkBank.
+func (d *DoctorService) banksWithoutRapidUrls(bankSlugs []string) []string {
+	banks := []string{}
+	for _, bankSlug := range bankSlugs {
+		data, err := os.ReadFile(filepath.Join(d.dataDir, constants.Bank, bankSlug, bankSlug+".json"))
+		if err != nil {
+			continue
+		}
+		bankDetails := port.CLIBankDetails{}
+		if err := json.Unmarshal(data, &bankDetails); err == nil && len(bankDetails.RapidUrls) == 0 {
+			banks = append(banks, bankSlug)
+		}
+	}
+	return banks
+}
+
+// checkBank probes the bank's rapid_urls, or rapidLinksURL for a bank without
+// its own, like the server does when it forwards.
+func (d *DoctorService) checkBank(bankSlug, rapidLinksURL string) {
+	subject := "bank " + bankSlug
+
+	configPath := filepath.Join(d.dataDir, constants.Bank, bankSlug, bankSlug+".json")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		d.fail(subject, "registered in core.json but its config is missing: "+err.Error(), "run rapid-bridge init bank --slug "+bankSlug+" --force, or rapid-bridge bank remove "+bankSlug)
+		return
+	}
+
+	bankDetails := port.CLIBankDetails{}
+	if err := json.Unmarshal(data, &bankDetails); err != nil {
+		d.fail(subject, "invalid config "+configPath+": "+err.Error(), "")
+		return
+	}
+
+	d.checkKeyFile(subject, "RSA public key", util.GetBankRSAPublicKeyPath(d.dataDir, bankSlug), false)
+	d.checkKeyFile(subject, "Ed25519 public key", util.GetBankEd25519PublicKeyPath(d.dataDir, bankSlug), false)
+
+	rapidUrls := bankDetails.RapidUrls
+	if len(rapidUrls) == 0 {
+		if rapidLinksURL == "" {
+			d.fail(subject, "no rapid_urls configured and rapid_links_url is empty", "run rapid-bridge bank refresh "+bankSlug+" --rapidUrl <url> or add rapid_urls to "+configPath)
+			return
+		}
+		d.ok(subject, "no rapid_urls configured, falls back to rapid_links_url "+rapidLinksURL)
+		rapidUrls = []string{rapidLinksURL}
+	}
+
+	tlsConfig := &tls.Config{}
+	if bankDetails.TLS != nil {
+		tlsConfig, err = tlsconfig.NewClientConfig(*bankDetails.TLS)
+		if err != nil {
+			d.fail(subject, "invalid tls settings: "+err.Error(), "fix the tls section of "+configPath)
+			return
+		}
+		d.ok(subject, "tls settings load")
+	}
+
+	if d.skipNetwork {
+		return
+	}
+
+	reachable := 0
+	for _, rapidUrl := range rapidUrls {
+		if err := d.probe(rapidUrl, tlsConfig); err != nil {
+			d.warn(subject, rapidUrl+" unreachable: "+err.Error(), "check the url, DNS and firewall rules from this host")
+			continue
+		}
+		reachable++
+		d.ok(subject, rapidUrl+" reachable")
+	}
+
+	if reachable == 0 {
+		d.fail(subject, "none of its urls are reachable", "requests to this bank will fail until one of them is")
+	}
+}
+
+// checkOrphans reports directories under dir that are not registered in
+// core.json, typically left over from a failed init or a manual edit.
+func (d *DoctorService) checkOrphans(dir string, registered []string) {
+	entries, err := os.ReadDir(filepath.Join(d.dataDir, dir))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || slices.Contains(registered, entry.Name()) {
+			continue
+		}
+		d.warn(dir+" "+entry.Name(), "directory exists but is not registered in core.json", "register it again with rapid-bridge init "+dir+" or delete "+filepath.Join(d.dataDir, dir, entry.Name()))
+	}
+}
+
+func (d *DoctorService) checkKeyFile(subject, name, path string, isPrivate bool) (any, bool) {
+	key, err := keys.ReadAndValidateKeyFile(path, isPrivate)
+	if err != nil {
+		d.fail(subject, name+": "+err.Error(), "re-import or regenerate the key, see "+path)
+		return nil, false
+	}
+
+	d.ok(subject, name+" valid")
+	return key, true
+}
+
+func (d *DoctorService) checkKeyPair(subject, name string, privateKey, publicKey any) {
+	if !keys.PairMatches(privateKey, publicKey) {
+		d.fail(subject, name+" private and public keys do not belong together", "re-import the matching pair with rapid-bridge init app --force")
+		return
+	}
+
+	d.ok(subject, name+" key pair matches")
+}
+
+func (d *DoctorService) checkExpiry(subject, name string, validUntil time.Time) {
+	if validUntil.IsZero() {
+		d.warn(subject, name+" have no expiry date", "")
+		return
+	}
+
+	remaining := time.Until(validUntil)
+	switch {
+	case remaining <= 0:
+		d.fail(subject, name+" expired on "+validUntil.Format(time.DateOnly), "rotate the keys with rapid-bridge init app --force")
+	case remaining < doctorExpiryWarningDays*24*time.Hour:
+		d.warn(subject, name+" expire on "+validUntil.Format(time.DateOnly), "rotate the keys soon with rapid-bridge init app --force")
+	default:
+		d.ok(subject, name+" valid until "+validUntil.Format(time.DateOnly))
+	}
+}
+
+// probe opens a TCP connection to the url, and completes a TLS handshake with
+// tlsConfig for https urls.
+func (d *DoctorService) probe(rawUrl string, tlsConfig *tls.Config) error {
+	address, err := upstream.DialAddress(rawUrl)
+	if err != nil {
+		return err
+	}
+
+	parsedUrl, _ := url.Parse(rawUrl)
+	dialer := &net.Dialer{Timeout: d.probeTimeout}
+
+	if parsedUrl.Scheme == "https" {
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = parsedUrl.Hostname()
+		}
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (d *DoctorService) ok(subject, detail string) {
+	d.report.Checks = append(d.report.Checks, DoctorCheck{Subject: subject, Status: DoctorOK, Detail: detail})
+}
+
+func (d *DoctorService) warn(subject, detail, hint string) {
+	d.report.Checks = append(d.report.Checks, DoctorCheck{Subject: subject, Status: DoctorWarn, Detail: detail, Hint: hint})
+	d.report.Warnings++
+}
+
+func (d *DoctorService) fail(subject, detail, hint string) {
+	d.report.Checks = append(d.report.Checks, DoctorCheck{Subject: subject, Status: DoctorFail, Detail: detail, Hint: hint})
+	d.report.Failures++
+}
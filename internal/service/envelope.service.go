@@ -0,0 +1,398 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/domain/security"
+	"rapid-bridge/internal/dto/application"
+	"rapid-bridge/internal/dto/rapid"
+	hybridcrypto "rapid-bridge/pkg/security/crypto"
+	"rapid-bridge/pkg/util"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+var ErrSignatureInvalid = stderrors.New("signature verification failed")
+var ErrDecryptionFailed = stderrors.New("decryption failed")
+
+// EnvelopePart describes one base64 encoded part of an envelope message.
+type EnvelopePart struct {
+	Length int    `json:"length"`
+	Hex    string `json:"hex,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// EnvelopeInfo is the structure of an envelope as seen without any keys.
+type EnvelopeInfo struct {
+	From            string       `json:"from"`
+	To              string       `json:"to"`
+	KeyVersion      string       `json:"key_version"`
+	Parts           int          `json:"parts"`
+	Ciphertext      EnvelopePart `json:"ciphertext"`
+	EncryptedAESKey EnvelopePart `json:"encrypted_aes_key"`
+	Nonce           EnvelopePart `json:"nonce"`
+	Signature       EnvelopePart `json:"signature"`
+	// size of the RSA modulus implied by the encrypted AES key
+	RSAKeyBits int `json:"rsa_key_bits,omitempty"`
+}
+
+// EnvelopeService reproduces outside the server what the bridge does to a
+// message: sealing application requests for a bank and opening the bank's
+// responses, using the keys in the data directory.
+type EnvelopeService struct {
+	loader   port.KeyLoader
+	security security.Security
+	config   port.CLIConfig
+	logger   port.Logger
+}
+
+func NewEnvelopeService(keyLoader port.KeyLoader, security security.Security, config port.CLIConfig, logger port.Logger) *EnvelopeService {
+	return &EnvelopeService{
+		loader:   keyLoader,
+		security: security,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// ParseEnvelope accepts either a rapid resource request or a rapid resource
+// response, whose envelope is nested under "data".
+func ParseEnvelope(data []byte) (rapid.RapidResourceRequest, error) {
+	var wrapped struct {
+		Data *rapid.RapidResourceRequest `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return rapid.RapidResourceRequest{}, fmt.Errorf("invalid envelope: %w", err)
+	}
+	if wrapped.Data != nil {
+		return *wrapped.Data, nil
+	}
+
+	envelope := rapid.RapidResourceRequest{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return rapid.RapidResourceRequest{}, fmt.Errorf("invalid envelope: %w", err)
+	}
+	return envelope, nil
+}
+
+// Seal builds the rapid resource request the server would send for an
+// application request payload. The payload goes through the same request
+// struct as on the server, so the plaintext is byte for byte identical.
+func (e *EnvelopeService) Seal(from, to, keyVersion string, payload []byte) (rapid.RapidResourceRequest, error) {
+	request := application.ResourceRequest{}
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return rapid.RapidResourceRequest{}, fmt.Errorf("invalid request payload: %w", err)
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	return e.SealRaw(from, to, keyVersion, data)
+}
+
+// SealRequest seals a request for an upstream path the way the server does:
+// the query parameters go into the encrypted payload, and the method and
+// path into signed envelope metadata.
+func (e *EnvelopeService) SealRequest(from, to, keyVersion, method, upstreamPath string, query url.Values, payload []byte) (rapid.RapidResourceRequest, error) {
+	request := application.ResourceRequest{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return rapid.RapidResourceRequest{}, fmt.Errorf("invalid request payload: %w", err)
+		}
+	}
+	request.Query = nil
+	if len(query) > 0 {
+		request.Query = query
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	envelope, err := e.SealRaw(from, to, keyVersion, data)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	ed25519PrivateKey, err := e.applicationEd25519PrivateKey(from, envelope.KeyVersion)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	envelope.Metadata = &rapid.EnvelopeMetadata{
+		Method:         method,
+		Path:           upstreamPath,
+		QueryInPayload: len(request.Query) > 0,
+	}
+	signingBytes, err := envelope.Metadata.SigningBytes(envelope.Message)
+	if err == nil {
+		envelope.MetadataSignature, err = e.security.Sign(ed25519PrivateKey, signingBytes)
+	}
+	if err != nil {
+		e.logger.Error("Failed to sign envelope metadata", zap.String("error", err.Error()))
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	return envelope, nil
+}
+
+// SealRaw encrypts data for the bank as is and signs it with the
+// application's key.
+func (e *EnvelopeService) SealRaw(from, to, keyVersion string, data []byte) (rapid.RapidResourceRequest, error) {
+	keyVersion, err := e.resolveKeyVersion(from, keyVersion)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	ed25519PrivateKey, err := e.applicationEd25519PrivateKey(from, keyVersion)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	if !slices.Contains(e.config.GetRegisteredBanks(), to) {
+		return rapid.RapidResourceRequest{}, fmt.Errorf("%w: %s", ErrBankNotRegistered, to)
+	}
+	bankRsaPublicKey, err := e.loader.LoadPublicKey(util.GetBankRSAPublicKeyPath(e.config.GetDataDir(), to))
+	if err != nil {
+		e.logger.Error("Failed to read public keys", zap.String("error", err.Error()))
+		return rapid.RapidResourceRequest{}, err
+	}
+	rsaPublicKey, ok := bankRsaPublicKey.(*rsa.PublicKey)
+	if !ok {
+		return rapid.RapidResourceRequest{}, fmt.Errorf("bank %s RSA public key is not an RSA key", to)
+	}
+
+	ciphertext, encryptedAESKey, nonce, err := e.security.Encrypt(data, rsaPublicKey)
+	if err != nil {
+		e.logger.Error("Failed to encrypt payload", zap.String("error", err.Error()))
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	signature, err := e.security.CreateDigitalSignature(ed25519PrivateKey, ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		e.logger.Error("Failed to sign payload", zap.String("error", err.Error()))
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	message, err := e.security.CreateBase64Encrypted(ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	return rapid.RapidResourceRequest{
+		From:       from,
+		To:         to,
+		Message:    message,
+		Signature:  signature,
+		KeyVersion: keyVersion,
+	}, nil
+}
+
+// Open verifies an envelope a bank sent to an application and returns the
+// decrypted payload, the same way the server handles a rapid links response.
+func (e *EnvelopeService) Open(envelope rapid.RapidResourceRequest) ([]byte, error) {
+	if slices.Contains(e.config.GetRegisteredBanks(), envelope.To) {
+		return nil, fmt.Errorf("envelope is addressed to bank %s, whose private key is not held by the bridge", envelope.To)
+	}
+
+	keyVersion, err := e.resolveKeyVersion(envelope.To, envelope.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPrivateKey, err := e.loader.LoadPrivateKey(util.GetRSAPrivateKeyPath(e.config.GetDataDir(), envelope.To, keyVersion))
+	if err != nil {
+		e.logger.Error("Failed to read private keys", zap.String("error", err.Error()))
+		return nil, err
+	}
+	applicationRsaPrivateKey, ok := rsaPrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("application %s RSA private key is not an RSA key", envelope.To)
+	}
+
+	if err := e.Verify(envelope); err != nil {
+		return nil, err
+	}
+
+	ciphertext, encryptedAESKey, nonce, err := e.security.DecodeBase64Encrypted(envelope.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := e.security.Decrypt(applicationRsaPrivateKey, ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecryptionFailed, err.Error())
+	}
+
+	return plaintext, nil
+}
+
+// Sign replaces the envelope signature with one made by the sending
+// application's key.
+func (e *EnvelopeService) Sign(envelope rapid.RapidResourceRequest) (rapid.RapidResourceRequest, error) {
+	keyVersion, err := e.resolveKeyVersion(envelope.From, envelope.KeyVersion)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	ed25519PrivateKey, err := e.applicationEd25519PrivateKey(envelope.From, keyVersion)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	ciphertext, encryptedAESKey, nonce, err := e.security.DecodeBase64Encrypted(envelope.Message)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	signature, err := e.security.CreateDigitalSignature(ed25519PrivateKey, ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		return rapid.RapidResourceRequest{}, err
+	}
+
+	envelope.Signature = signature
+	envelope.KeyVersion = keyVersion
+	return envelope, nil
+}
+
+// Verify checks the envelope signature against the public key of its sender,
+// which may be either a registered application or a registered bank.
+func (e *EnvelopeService) Verify(envelope rapid.RapidResourceRequest) error {
+	dataDir := e.config.GetDataDir()
+
+	var publicKeyPath string
+	switch {
+	case slices.Contains(e.config.GetRegisteredBanks(), envelope.From):
+		publicKeyPath = util.GetBankEd25519PublicKeyPath(dataDir, envelope.From)
+	case slices.Contains(e.config.GetRegisteredApplications(), envelope.From):
+		keyVersion, err := e.resolveKeyVersion(envelope.From, envelope.KeyVersion)
+		if err != nil {
+			return err
+		}
+		publicKeyPath = util.GetEd25519PublicKeyPath(dataDir, envelope.From, keyVersion)
+	default:
+		return fmt.Errorf("sender %s is neither a registered application nor a registered bank", envelope.From)
+	}
+
+	publicKey, err := e.loader.LoadPublicKey(publicKeyPath)
+	if err != nil {
+		e.logger.Error("Failed to read public keys", zap.String("error", err.Error()))
+		return err
+	}
+	senderPublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("%s Ed25519 public key is not an Ed25519 key", envelope.From)
+	}
+
+	if err := e.security.VerifyDigitalSignature(envelope.Message, envelope.Signature, senderPublicKey); err != nil {
+		e.logger.Error("Failed to verify digital signature", zap.String("error", err.Error()))
+		return fmt.Errorf("%w for %s", ErrSignatureInvalid, envelope.From)
+	}
+
+	if envelope.Metadata == nil && envelope.MetadataSignature != "" {
+		return fmt.Errorf("%w for %s: metadata signature without metadata", ErrSignatureInvalid, envelope.From)
+	}
+	if envelope.Metadata != nil {
+		signingBytes, err := envelope.Metadata.SigningBytes(envelope.Message)
+		if err != nil {
+			return fmt.Errorf("%w for %s metadata: %w", ErrSignatureInvalid, envelope.From, err)
+		}
+		if err := e.security.Verify(signingBytes, envelope.MetadataSignature, senderPublicKey); err != nil {
+			e.logger.Error("Failed to verify metadata signature", zap.String("error", err.Error()))
+			return fmt.Errorf("%w for %s metadata", ErrSignatureInvalid, envelope.From)
+		}
+	}
+
+	return nil
+}
+
+// Inspect decodes the envelope structure. It needs no keys and never fails,
+// problems are reported per part instead.
+func (e *EnvelopeService) Inspect(envelope rapid.RapidResourceRequest) EnvelopeInfo {
+	parts := hybridcrypto.SplitMessage(envelope.Message)
+
+	info := EnvelopeInfo{
+		From:       envelope.From,
+		To:         envelope.To,
+		KeyVersion: envelope.KeyVersion,
+		Parts:      len(parts),
+		Signature:  inspectPart(envelope.Signature, false),
+	}
+
+	if len(parts) != 3 {
+		info.Ciphertext.Error = "invalid message format"
+		return info
+	}
+
+	info.Ciphertext = inspectPart(parts[0], false)
+	info.EncryptedAESKey = inspectPart(parts[1], false)
+	info.Nonce = inspectPart(parts[2], true)
+	if info.EncryptedAESKey.Error == "" {
+		info.RSAKeyBits = info.EncryptedAESKey.Length * 8
+	}
+
+	return info
+}
+
+func inspectPart(encoded string, withHex bool) EnvelopePart {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return EnvelopePart{Error: err.Error()}
+	}
+
+	part := EnvelopePart{Length: len(decoded)}
+	if withHex {
+		part.Hex = hex.EncodeToString(decoded)
+	}
+	return part
+}
+
+func (e *EnvelopeService) resolveKeyVersion(applicationSlug, keyVersion string) (string, error) {
+	return resolveKeyVersion(e.config, applicationSlug, keyVersion)
+}
+
+// resolveKeyVersion falls back to the application's current key version.
+func resolveKeyVersion(config port.CLIConfig, applicationSlug, keyVersion string) (string, error) {
+	if !slices.Contains(config.GetRegisteredApplications(), applicationSlug) {
+		return "", fmt.Errorf("%w: %s", ErrApplicationNotRegistered, applicationSlug)
+	}
+
+	if keyVersion != "" {
+		return keyVersion, nil
+	}
+
+	applicationDetails, err := config.LoadApplicationDetails(applicationSlug)
+	if err != nil {
+		return "", err
+	}
+	if applicationDetails.KeyVersion == "" {
+		return "", fmt.Errorf("application %s has no current key version", applicationSlug)
+	}
+
+	return applicationDetails.KeyVersion, nil
+}
+
+func (e *EnvelopeService) applicationEd25519PrivateKey(applicationSlug, keyVersion string) (ed25519.PrivateKey, error) {
+	privateKey, err := e.loader.LoadPrivateKey(util.GetEd25519PrivateKeyPath(e.config.GetDataDir(), applicationSlug, keyVersion))
+	if err != nil {
+		e.logger.Error("Failed to read private keys", zap.String("error", err.Error()))
+		return nil, err
+	}
+
+	ed25519PrivateKey, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("application %s Ed25519 private key is not an Ed25519 key", applicationSlug)
+	}
+	return ed25519PrivateKey, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/adapter/upstream"
+)
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+type EndpointHealth struct {
+	Url     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+}
+
+type BankHealth struct {
+	Slug      string           `json:"slug"`
+	Circuit   string           `json:"circuit"`
+	Endpoints []EndpointHealth `json:"endpoints"`
+}
+
+// HealthReport is served on /health. The bridge itself is up whenever it
+// answers; Status is degraded when a bank cannot currently be reached.
+type HealthReport struct {
+	Status string       `json:"status"`
+	Banks  []BankHealth `json:"banks"`
+}
+
+// HealthService reports the state of every bank's circuit and endpoints as
+// the server currently sees them. It does not probe anything itself.
+type HealthService struct {
+	config    port.ServerConfig
+	upstreams port.UpstreamPool
+	breakers  port.CircuitBreaker
+}
+
+func NewHealthService(config port.ServerConfig, upstreams port.UpstreamPool, breakers port.CircuitBreaker) *HealthService {
+	return &HealthService{
+		config:    config,
+		upstreams: upstreams,
+		breakers:  breakers,
+	}
+}
+
+func (h *HealthService) Report() HealthReport {
+	report := HealthReport{Status: HealthOK, Banks: []BankHealth{}}
+
+	for _, bankSlug := range h.config.GetRegisteredBanks() {
+		bankHealth := BankHealth{
+			Slug:      bankSlug,
+			Circuit:   h.breakers.State(bankSlug),
+			Endpoints: []EndpointHealth{},
+		}
+
+		rapidUrls, _ := h.config.GetBankRapidUrls(bankSlug)
+		healthy := 0
+		for _, rapidUrl := range rapidUrls {
+			endpointHealth := EndpointHealth{Url: rapidUrl, Healthy: h.upstreams.IsHealthy(rapidUrl)}
+			if endpointHealth.Healthy {
+				healthy++
+			}
+			bankHealth.Endpoints = append(bankHealth.Endpoints, endpointHealth)
+		}
+
+		if bankHealth.Circuit != upstream.CircuitClosed || healthy == 0 {
+			report.Status = HealthDegraded
+		}
+		report.Banks = append(report.Banks, bankHealth)
+	}
+
+	return report
+}
@@ -7,6 +7,8 @@ import (
 	"rapid-bridge/constants"
 	"rapid-bridge/domain/keys"
 	"rapid-bridge/domain/port"
+	httpclient "rapid-bridge/internal/adapter/http_client"
+	"rapid-bridge/internal/adapter/upstream"
 	hybridcrypto "rapid-bridge/pkg/security/crypto"
 	"rapid-bridge/pkg/util"
 
@@ -45,13 +47,13 @@ func (k *KeyService) GenerateAndSaveApplicationKeys(applicationSlug, ulid string
 		return err
 	}
 
-	err = k.KeySaver.SaveRSAPrivateKeyToPEM(rsaPrivateKey, util.GetRSAPrivateKeyPath(applicationSlug, ulid))
+	err = k.KeySaver.SaveRSAPrivateKeyToPEM(rsaPrivateKey, util.GetRSAPrivateKeyPath(k.Config.GetDataDir(), applicationSlug, ulid))
 	if err != nil {
 		k.Logger.Error("Error while saving rsa private key to pem", zap.String("error", err.Error()))
 		return err
 	}
 
-	err = k.KeySaver.SaveRSAPublicKeyToPEM(rsaPublicKey, util.GetRSAPublicKeyPath(applicationSlug, ulid))
+	err = k.KeySaver.SaveRSAPublicKeyToPEM(rsaPublicKey, util.GetRSAPublicKeyPath(k.Config.GetDataDir(), applicationSlug, ulid))
 	if err != nil {
 		k.Logger.Error("Error while saving rsa public key to pem", zap.String("error", err.Error()))
 		return err
@@ -63,13 +65,13 @@ func (k *KeyService) GenerateAndSaveApplicationKeys(applicationSlug, ulid string
 		return err
 	}
 
-	err = k.KeySaver.SaveEd25519PrivateKeyToPEM(ed25519PrivateKey, util.GetEd25519PrivateKeyPath(applicationSlug, ulid))
+	err = k.KeySaver.SaveEd25519PrivateKeyToPEM(ed25519PrivateKey, util.GetEd25519PrivateKeyPath(k.Config.GetDataDir(), applicationSlug, ulid))
 	if err != nil {
 		k.Logger.Error("Error while saving ed25519 private key to pem", zap.String("error", err.Error()))
 		return err
 	}
 
-	err = k.KeySaver.SaveEd25519PublicKeyToPEM(ed25519PublicKey, util.GetEd25519PublicKeyPath(applicationSlug, ulid))
+	err = k.KeySaver.SaveEd25519PublicKeyToPEM(ed25519PublicKey, util.GetEd25519PublicKeyPath(k.Config.GetDataDir(), applicationSlug, ulid))
 	if err != nil {
 		k.Logger.Error("Error while saving ed25519 public key to pem", zap.String("error", err.Error()))
 		return err
@@ -114,20 +116,24 @@ func (k *KeyService) UseExistingApplicationKeys(applicationSlug, ulid string, rs
 		return err
 	}
 
-	if err := k.KeySaver.SaveRSAPrivateKeyToPEM(rsaPrivateKey.(*rsa.PrivateKey), util.GetRSAPrivateKeyPath(applicationSlug, ulid)); err != nil {
-		fmt.Println("Error while saving rsa private key to pem file: ", err)
+	if err := k.KeySaver.SaveRSAPrivateKeyToPEM(rsaPrivateKey.(*rsa.PrivateKey), util.GetRSAPrivateKeyPath(k.Config.GetDataDir(), applicationSlug, ulid)); err != nil {
+		k.Logger.Error("Error while saving rsa private key to pem file", zap.String("error", err.Error()))
+		return err
 	}
 
-	if err := k.KeySaver.SaveRSAPublicKeyToPEM(rsaPublicKey.(*rsa.PublicKey), util.GetRSAPublicKeyPath(applicationSlug, ulid)); err != nil {
-		fmt.Println("Error while saving rsa public key to pem file: ", err)
+	if err := k.KeySaver.SaveRSAPublicKeyToPEM(rsaPublicKey.(*rsa.PublicKey), util.GetRSAPublicKeyPath(k.Config.GetDataDir(), applicationSlug, ulid)); err != nil {
+		k.Logger.Error("Error while saving rsa public key to pem file", zap.String("error", err.Error()))
+		return err
 	}
 
-	if err := k.KeySaver.SaveEd25519PrivateKeyToPEM(ed25519PrivateKey.(ed25519.PrivateKey), util.GetEd25519PrivateKeyPath(applicationSlug, ulid)); err != nil {
-		fmt.Println("Error while saving ed25519 private key to pem file: ", err)
+	if err := k.KeySaver.SaveEd25519PrivateKeyToPEM(ed25519PrivateKey.(ed25519.PrivateKey), util.GetEd25519PrivateKeyPath(k.Config.GetDataDir(), applicationSlug, ulid)); err != nil {
+		k.Logger.Error("Error while saving ed25519 private key to pem file", zap.String("error", err.Error()))
+		return err
 	}
 
-	if err := k.KeySaver.SaveEd25519PublicKeyToPEM(ed25519PublicKey.(ed25519.PublicKey), util.GetEd25519PublicKeyPath(applicationSlug, ulid)); err != nil {
-		fmt.Println("Error while saving ed25519 public key to pem file: ", err)
+	if err := k.KeySaver.SaveEd25519PublicKeyToPEM(ed25519PublicKey.(ed25519.PublicKey), util.GetEd25519PublicKeyPath(k.Config.GetDataDir(), applicationSlug, ulid)); err != nil {
+		k.Logger.Error("Error while saving ed25519 public key to pem file", zap.String("error", err.Error()))
+		return err
 	}
 
 	return nil
@@ -151,22 +157,48 @@ func (k *KeyService) UseExistingBankKeys(bankSlug string, rsaPublicKeyPath, ed25
 		return err
 	}
 
-	if err := k.KeySaver.SaveRSAPublicKeyToPEM(rsaPublicKey.(*rsa.PublicKey), util.GetBankRSAPublicKeyPath(bankSlug)); err != nil {
-		fmt.Println("Error while saving bank rsa public key to pem file: ", err)
+	if err := k.KeySaver.SaveRSAPublicKeyToPEM(rsaPublicKey.(*rsa.PublicKey), util.GetBankRSAPublicKeyPath(k.Config.GetDataDir(), bankSlug)); err != nil {
+		k.Logger.Error("Error while saving bank rsa public key to pem file", zap.String("error", err.Error()))
+		return err
 	}
 
-	if err := k.KeySaver.SaveEd25519PublicKeyToPEM(ed25519PublicKey.(ed25519.PublicKey), util.GetBankEd25519PublicKeyPath(bankSlug)); err != nil {
-		fmt.Println("Error while saving bank ed25519 public key to pem file: ", err)
+	if err := k.KeySaver.SaveEd25519PublicKeyToPEM(ed25519PublicKey.(ed25519.PublicKey), util.GetBankEd25519PublicKeyPath(k.Config.GetDataDir(), bankSlug)); err != nil {
+		k.Logger.Error("Error while saving bank ed25519 public key to pem file", zap.String("error", err.Error()))
+		return err
 	}
 
 	return nil
 }
 
-func (k *KeyService) FetchAndSaveBankKeys(rapidUrl, bankSlug string) error {
+// FetchAndSaveBankKeys fetches the bank's public keys from the first rapid
+// url that answers, connecting with the bank's upstream TLS settings.
+func (k *KeyService) FetchAndSaveBankKeys(rapidUrls []string, bankSlug string, upstreamTLS *port.UpstreamTLS) error {
+	if len(rapidUrls) == 0 {
+		return fmt.Errorf("no rapid url given for bank: %s", bankSlug)
+	}
+
+	httpClient := k.HttpClient
+	if upstreamTLS != nil {
+		client, err := upstream.NewHTTPClient(upstreamTLS)
+		if err != nil {
+			k.Logger.Error("Invalid upstream tls configuration of bank", zap.String("error", err.Error()))
+			return err
+		}
+		httpClient = httpclient.NewHttpClientWithClient(k.Logger, client)
+	}
+
 	k.Logger.Info("Fetching bank's rsa and ed25519 public key from rapid")
-	bankRSAPublicKey, bankED25519PublicKey, err := k.FetchBankPublicKeys(rapidUrl)
+
+	var bankRSAPublicKey, bankED25519PublicKey string
+	var err error
+	for _, rapidUrl := range rapidUrls {
+		bankRSAPublicKey, bankED25519PublicKey, err = k.fetchBankPublicKeys(httpClient, rapidUrl)
+		if err == nil {
+			break
+		}
+		k.Logger.Error("Error while fetching public keys of bank", zap.String("url", rapidUrl), zap.String("error", err.Error()))
+	}
 	if err != nil {
-		k.Logger.Error("Error while fetching public keys of bank", zap.String("error", err.Error()))
 		return err
 	}
 
@@ -185,8 +217,8 @@ func (k *KeyService) FetchAndSaveBankKeys(rapidUrl, bankSlug string) error {
 	bankRsaPubKey := bankRsaPublicKey.(*rsa.PublicKey)
 	bankEdPubKey := bankEdPublicKey.(ed25519.PublicKey)
 
-	rsaPubKeyPath := util.GetBankRSAPublicKeyPath(bankSlug)
-	edPubKeyPath := util.GetBankEd25519PublicKeyPath(bankSlug)
+	rsaPubKeyPath := util.GetBankRSAPublicKeyPath(k.Config.GetDataDir(), bankSlug)
+	edPubKeyPath := util.GetBankEd25519PublicKeyPath(k.Config.GetDataDir(), bankSlug)
 
 	if err := k.KeySaver.SaveRSAPublicKeyToPEM(bankRsaPubKey, rsaPubKeyPath); err != nil {
 		k.Logger.Error("Error while saving rsa public key of bank", zap.String("error", err.Error()))
@@ -202,7 +234,11 @@ func (k *KeyService) FetchAndSaveBankKeys(rapidUrl, bankSlug string) error {
 }
 
 func (k *KeyService) FetchBankPublicKeys(rapidUrl string) (string, string, error) {
-	pubKeyResponse, err := k.HttpClient.GET(rapidUrl+"/public-key", map[string]string{}, map[string]string{})
+	return k.fetchBankPublicKeys(k.HttpClient, rapidUrl)
+}
+
+func (k *KeyService) fetchBankPublicKeys(httpClient port.HTTPClient, rapidUrl string) (string, string, error) {
+	pubKeyResponse, err := httpClient.GET(rapidUrl+"/public-key", map[string]string{}, map[string]string{})
 	if err != nil {
 		k.Logger.Error("Failed to get public keys", zap.String("error", err.Error()))
 		return "", "", err
@@ -213,13 +249,13 @@ func (k *KeyService) FetchBankPublicKeys(rapidUrl string) (string, string, error
 	bankRsaPublicKey, ok := publicKeys["rsaPublicKey"].(string)
 	if !ok {
 		k.Logger.Error("Failed to get public keys", zap.String("error", "bank_rsa_public_key not found"))
-		return "", "", errors.NewRapidLinksError("bank_rsa_public_key not found", 500)
+		return "", "", errors.NewCodedError(errors.CodeConfigError, "bank_rsa_public_key not found")
 	}
 
 	bankEd25519PublicKey, ok := publicKeys["ed25519PublicKey"].(string)
 	if !ok {
 		k.Logger.Error("Failed to get public keys", zap.String("error", "bank_ed25519_public_key not found"))
-		return "", "", errors.NewRapidLinksError("bank_ed25519_public_key not found", 500)
+		return "", "", errors.NewCodedError(errors.CodeConfigError, "bank_ed25519_public_key not found")
 	}
 
 	k.Logger.Info("Bank public keys successfully fetched from rapid", zap.Int("status_code", pubKeyResponse.StatusCode))
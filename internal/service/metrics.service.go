@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/adapter"
+	"rapid-bridge/internal/adapter/upstream"
+	"rapid-bridge/internal/dto/rapid"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/metrics"
+	"rapid-bridge/pkg/util"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var upstreamDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rapid_bridge_upstream_duration_seconds",
+	Help:    "Time Rapid Links took to answer, per endpoint tried.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"bank", "outcome"})
+
+var upstreamErrors = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+	Name: "rapid_bridge_upstream_errors_total",
+	Help: "Resource requests failed because of the bank, by error code.",
+}, []string{"bank", "code"})
+
+var signatureFailures = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+	Name: "rapid_bridge_signature_failures_total",
+	Help: "Responses of Rapid Links whose signature did not verify.",
+}, []string{"bank"})
+
+var keyExpiry = prometheus.NewDesc("rapid_bridge_key_expiry_timestamp_seconds", "When the keys of a key version of an application expire, as a Unix timestamp.", []string{"application", "key_version", "key"}, nil)
+
+// upstreamOutcome labels an exchange with an endpoint by the status the bank
+// answered with, or by why it did not answer.
+func upstreamOutcome(response rapid.RapidResourceResponse, err error) string {
+	var unavailable *adapter.UnavailableError
+	var upstreamErr errors.UpstreamError
+	switch {
+	case err == nil:
+		return strconv.Itoa(response.StatusCode)
+	case stderrors.As(err, &unavailable):
+		return strconv.Itoa(unavailable.StatusCode)
+	case stderrors.As(err, &upstreamErr):
+		return strconv.Itoa(upstreamErr.UpstreamStatus)
+	case upstream.IsConnectError(err):
+		return "connect_error"
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// upstreamErrorCode is the code the caller gets for a failed exchange with
+// the bank.
+func upstreamErrorCode(err error) errors.Code {
+	if code, ok := errors.CodeOf(err); ok {
+		return code
+	}
+	var upstreamErr errors.UpstreamError
+	if stderrors.As(err, &upstreamErr) {
+		return errors.CodeUpstreamError
+	}
+	return errors.CodeInternal
+}
+
+// MetricsService computes the metrics that are read from the data directory
+// when scraped rather than counted as requests come in. It is a
+// prometheus.Collector.
+type MetricsService struct {
+	config port.ServerConfig
+}
+
+func NewMetricsService(config port.ServerConfig) *MetricsService {
+	return &MetricsService{config: config}
+}
+
+func (m *MetricsService) Describe(descs chan<- *prometheus.Desc) {
+	descs <- keyExpiry
+}
+
+// Collect reports the key expiry of every key version of the registered
+// applications. Only the current version has its expiry in the application
+// config; older versions expire as long after their ULID timestamp as the
+// current keys do after theirs. Applications whose config cannot be read are
+// left out; doctor reports them.
+func (m *MetricsService) Collect(collected chan<- prometheus.Metric) {
+	for _, applicationSlug := range m.config.GetRegisteredApplications() {
+		data, err := os.ReadFile(filepath.Join(m.config.GetDataDir(), constants.Application, applicationSlug, applicationSlug+".json"))
+		if err != nil {
+			continue
+		}
+		applicationDetails := port.CLIApplicationDetails{}
+		if err := json.Unmarshal(data, &applicationDetails); err != nil || applicationDetails.KeyVersion == "" {
+			continue
+		}
+		currentCreated, ok := util.KeyVersionTime(applicationDetails.KeyVersion)
+		if !ok {
+			continue
+		}
+
+		for _, keyVersion := range util.ApplicationKeyVersions(m.config.GetDataDir(), applicationSlug) {
+			created, _ := util.KeyVersionTime(keyVersion)
+			for key, validUntil := range map[string]time.Time{
+				"rsa":     applicationDetails.RSAKeysValidUntil,
+				"ed25519": applicationDetails.Ed25519KeysValidUntil,
+			} {
+				if validUntil.IsZero() {
+					continue
+				}
+				if keyVersion != applicationDetails.KeyVersion {
+					validUntil = created.Add(validUntil.Sub(currentCreated))
+				}
+				collected <- prometheus.MustNewConstMetric(keyExpiry, prometheus.GaugeValue, float64(validUntil.Unix()), applicationSlug, keyVersion, key)
+			}
+		}
+	}
+}
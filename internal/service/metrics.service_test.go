@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// metricsConfig registers the application "app" in dataDir.
+type metricsConfig struct {
+	port.ServerConfig
+	dataDir string
+}
+
+func (c metricsConfig) GetDataDir() string { return c.dataDir }
+
+func (metricsConfig) GetRegisteredApplications() []string { return []string{"app"} }
+
+func TestMetricsServiceKeyExpiryPerKeyVersion(t *testing.T) {
+	dataDir := t.TempDir()
+	created := time.Unix(1700000000, 0)
+	previous := ulid.MustNew(ulid.Timestamp(created), ulid.DefaultEntropy()).String()
+	current := ulid.MustNew(ulid.Timestamp(created.AddDate(0, 0, 30)), ulid.DefaultEntropy()).String()
+
+	applicationDir := filepath.Join(dataDir, constants.Application, "app")
+	for _, keyVersion := range []string{previous, current} {
+		if err := os.MkdirAll(filepath.Join(applicationDir, keyVersion), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := json.Marshal(port.CLIApplicationDetails{
+		Slug:                  "app",
+		KeyVersion:            current,
+		RSAKeysValidUntil:     created.AddDate(0, 0, 30+90),
+		Ed25519KeysValidUntil: created.AddDate(0, 0, 30+365),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(applicationDir, "app.json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	series := func(keyVersion, key string, validUntil time.Time) string {
+		return fmt.Sprintf("rapid_bridge_key_expiry_timestamp_seconds{application=\"app\",key=%q,key_version=%q} %d\n", key, keyVersion, validUntil.Unix())
+	}
+	want := "# HELP rapid_bridge_key_expiry_timestamp_seconds When the keys of a key version of an application expire, as a Unix timestamp.\n" +
+		"# TYPE rapid_bridge_key_expiry_timestamp_seconds gauge\n" +
+		series(previous, "ed25519", created.AddDate(0, 0, 365)) +
+		series(current, "ed25519", created.AddDate(0, 0, 30+365)) +
+		series(previous, "rsa", created.AddDate(0, 0, 90)) +
+		series(current, "rsa", created.AddDate(0, 0, 30+90))
+
+	if err := testutil.CollectAndCompare(NewMetricsService(metricsConfig{dataDir: dataDir}), strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -36,7 +36,7 @@ func NewPlaygroundService(logger port.Logger, app *setup.CLIApplication, keyLoad
 
 func (s *PlaygroundService) getApplicationDetails(applicationSlug string) (ApplicationDetails, error) {
 
-	applicationDetails := config.LoadApplicationSpecificConfig(applicationSlug)
+	applicationDetails := config.LoadApplicationSpecificConfig(s.app.Config.GetDataDir(), applicationSlug)
 
 	// rsaPrivateKeyPath := applicationDetails.RSAPrivateKeyPath
 	// rsaPrivateKey, err := s.keyLoader.LoadPrivateKey(rsaPrivateKeyPath)
@@ -118,7 +118,8 @@ func (s *PlaygroundService) RegisterApplication(request playground.ApplicationRe
 
 		s.app.Config.AddRegisteredApplications(request.Slug)
 		s.app.Config.AddApplicationSlug(request.Slug)
-		s.app.Config.AddApplicationKeysPaths(constants.RapidBridgeData+"/application/"+request.Slug+"/"+ulid+"/rsa_private_key.pem", constants.RapidBridgeData+"/application/"+request.Slug+"/"+ulid+"/rsa_public_key.pem", constants.RapidBridgeData+"/application/"+request.Slug+"/"+ulid+"/ed25519_private_key.pem", constants.RapidBridgeData+"/application/"+request.Slug+"/"+ulid+"/ed25519_public_key.pem")
+		dataDir := s.app.Config.GetDataDir()
+		s.app.Config.AddApplicationKeysPaths(util.GetRSAPrivateKeyPath(dataDir, request.Slug, ulid), util.GetRSAPublicKeyPath(dataDir, request.Slug, ulid), util.GetEd25519PrivateKeyPath(dataDir, request.Slug, ulid), util.GetEd25519PublicKeyPath(dataDir, request.Slug, ulid))
 		s.app.Config.AddKeysValidityPeriod(constants.EncryptionKeyValidityPeriod, constants.SigningKeyValidityPeriod)
 		s.app.Config.AddApplicationUlid(ulid)
 
@@ -0,0 +1,240 @@
+package service
+
+import (
+	stderrors "errors"
+	"fmt"
+	"path/filepath"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	hybridcrypto "rapid-bridge/pkg/security/crypto"
+	"rapid-bridge/pkg/util"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+var ErrApplicationNotRegistered = stderrors.New("application is not registered")
+var ErrBankNotRegistered = stderrors.New("bank is not registered")
+
+type ApplicationInfo struct {
+	port.CLIApplicationDetails
+	Slug        string   `json:"slug"`
+	Disabled    bool     `json:"disabled"`
+	KeyVersions []string `json:"key_versions"`
+	// set when the application config file could not be read
+	ConfigError string `json:"config_error,omitempty"`
+}
+
+type BankInfo struct {
+	port.CLIBankDetails
+	Slug                        string `json:"slug"`
+	RSAPublicKeyFingerprint     string `json:"rsa_public_key_fingerprint,omitempty"`
+	Ed25519PublicKeyFingerprint string `json:"ed25519_public_key_fingerprint,omitempty"`
+	// set when the bank config or keys could not be read
+	ConfigError string `json:"config_error,omitempty"`
+}
+
+type RegistryService struct {
+	config     port.CLIConfig
+	keyLoader  port.KeyLoader
+	keyService *KeyService
+	logger     port.Logger
+}
+
+func NewRegistryService(config port.CLIConfig, keyLoader port.KeyLoader, keyService *KeyService, logger port.Logger) *RegistryService {
+	return &RegistryService{
+		config:     config,
+		keyLoader:  keyLoader,
+		keyService: keyService,
+		logger:     logger,
+	}
+}
+
+func (r *RegistryService) ListApplications() []ApplicationInfo {
+	applications := make([]ApplicationInfo, 0, len(r.config.GetRegisteredApplications()))
+	for _, applicationSlug := range r.config.GetRegisteredApplications() {
+		applications = append(applications, r.applicationInfo(applicationSlug))
+	}
+	return applications
+}
+
+func (r *RegistryService) ShowApplication(applicationSlug string) (ApplicationInfo, error) {
+	if !slices.Contains(r.config.GetRegisteredApplications(), applicationSlug) {
+		return ApplicationInfo{}, fmt.Errorf("%w: %s", ErrApplicationNotRegistered, applicationSlug)
+	}
+	return r.applicationInfo(applicationSlug), nil
+}
+
+func (r *RegistryService) DisableApplication(applicationSlug string) error {
+	if !slices.Contains(r.config.GetRegisteredApplications(), applicationSlug) {
+		return fmt.Errorf("%w: %s", ErrApplicationNotRegistered, applicationSlug)
+	}
+
+	r.config.DisableApplication(applicationSlug)
+	if err := r.config.SaveConfigToFile(); err != nil {
+		r.logger.Error("Error while saving config", zap.String("error", err.Error()))
+		return err
+	}
+
+	r.logger.Info("Application disabled", zap.String("slug", applicationSlug))
+	return nil
+}
+
+func (r *RegistryService) EnableApplication(applicationSlug string) error {
+	if !slices.Contains(r.config.GetRegisteredApplications(), applicationSlug) {
+		return fmt.Errorf("%w: %s", ErrApplicationNotRegistered, applicationSlug)
+	}
+
+	r.config.EnableApplication(applicationSlug)
+	if err := r.config.SaveConfigToFile(); err != nil {
+		r.logger.Error("Error while saving config", zap.String("error", err.Error()))
+		return err
+	}
+
+	r.logger.Info("Application enabled", zap.String("slug", applicationSlug))
+	return nil
+}
+
+// RemoveApplication unregisters the application first, so a running server
+// stops serving it even if deleting the key directory fails afterwards.
+func (r *RegistryService) RemoveApplication(applicationSlug string, keepKeys bool) error {
+	if !slices.Contains(r.config.GetRegisteredApplications(), applicationSlug) {
+		return fmt.Errorf("%w: %s", ErrApplicationNotRegistered, applicationSlug)
+	}
+
+	r.config.RemoveRegisteredApplication(applicationSlug)
+	if err := r.config.SaveConfigToFile(); err != nil {
+		r.logger.Error("Error while saving config", zap.String("error", err.Error()))
+		return err
+	}
+
+	if keepKeys {
+		return nil
+	}
+
+	dataDir := r.config.GetDataDir()
+	if err := util.RemoveDirWithin(filepath.Join(dataDir, constants.Application), filepath.Join(dataDir, constants.Application, applicationSlug)); err != nil {
+		r.logger.Error("Error while removing application directory", zap.String("error", err.Error()))
+		return err
+	}
+
+	r.logger.Info("Application removed", zap.String("slug", applicationSlug))
+	return nil
+}
+
+func (r *RegistryService) ListBanks() []BankInfo {
+	banks := make([]BankInfo, 0, len(r.config.GetRegisteredBanks()))
+	for _, bankSlug := range r.config.GetRegisteredBanks() {
+		banks = append(banks, r.bankInfo(bankSlug))
+	}
+	return banks
+}
+
+func (r *RegistryService) ShowBank(bankSlug string) (BankInfo, error) {
+	if !slices.Contains(r.config.GetRegisteredBanks(), bankSlug) {
+		return BankInfo{}, fmt.Errorf("%w: %s", ErrBankNotRegistered, bankSlug)
+	}
+	return r.bankInfo(bankSlug), nil
+}
+
+// RefreshBank fetches the bank's public keys again from its configured rapid
+// urls, or from rapidUrls when given.
+func (r *RegistryService) RefreshBank(bankSlug string, rapidUrls []string) error {
+	if !slices.Contains(r.config.GetRegisteredBanks(), bankSlug) {
+		return fmt.Errorf("%w: %s", ErrBankNotRegistered, bankSlug)
+	}
+
+	bankDetails, err := r.config.LoadBankDetails(bankSlug)
+	if err != nil {
+		return err
+	}
+	if len(rapidUrls) == 0 {
+		rapidUrls = bankDetails.RapidUrls
+	}
+
+	if err := r.keyService.FetchAndSaveBankKeys(rapidUrls, bankSlug, bankDetails.TLS); err != nil {
+		return err
+	}
+
+	r.logger.Info("Bank public keys refreshed", zap.String("slug", bankSlug))
+	return nil
+}
+
+func (r *RegistryService) RemoveBank(bankSlug string, keepKeys bool) error {
+	if !slices.Contains(r.config.GetRegisteredBanks(), bankSlug) {
+		return fmt.Errorf("%w: %s", ErrBankNotRegistered, bankSlug)
+	}
+
+	r.config.RemoveRegisteredBank(bankSlug)
+	if err := r.config.SaveConfigToFile(); err != nil {
+		r.logger.Error("Error while saving config", zap.String("error", err.Error()))
+		return err
+	}
+
+	if keepKeys {
+		return nil
+	}
+
+	dataDir := r.config.GetDataDir()
+	if err := util.RemoveDirWithin(filepath.Join(dataDir, constants.Bank), filepath.Join(dataDir, constants.Bank, bankSlug)); err != nil {
+		r.logger.Error("Error while removing bank directory", zap.String("error", err.Error()))
+		return err
+	}
+
+	r.logger.Info("Bank removed", zap.String("slug", bankSlug))
+	return nil
+}
+
+func (r *RegistryService) applicationInfo(applicationSlug string) ApplicationInfo {
+	info := ApplicationInfo{
+		Slug:        applicationSlug,
+		Disabled:    slices.Contains(r.config.GetDisabledApplications(), applicationSlug),
+		KeyVersions: util.ApplicationKeyVersions(r.config.GetDataDir(), applicationSlug),
+	}
+
+	applicationDetails, err := r.config.LoadApplicationDetails(applicationSlug)
+	if err != nil {
+		info.ConfigError = err.Error()
+		return info
+	}
+	info.CLIApplicationDetails = *applicationDetails
+
+	return info
+}
+
+func (r *RegistryService) bankInfo(bankSlug string) BankInfo {
+	info := BankInfo{Slug: bankSlug}
+
+	bankDetails, err := r.config.LoadBankDetails(bankSlug)
+	if err != nil {
+		info.ConfigError = err.Error()
+		return info
+	}
+	info.CLIBankDetails = *bankDetails
+
+	dataDir := r.config.GetDataDir()
+
+	rsaFingerprint, err := r.publicKeyFingerprint(util.GetBankRSAPublicKeyPath(dataDir, bankSlug))
+	if err != nil {
+		info.ConfigError = err.Error()
+		return info
+	}
+	info.RSAPublicKeyFingerprint = rsaFingerprint
+
+	ed25519Fingerprint, err := r.publicKeyFingerprint(util.GetBankEd25519PublicKeyPath(dataDir, bankSlug))
+	if err != nil {
+		info.ConfigError = err.Error()
+		return info
+	}
+	info.Ed25519PublicKeyFingerprint = ed25519Fingerprint
+
+	return info
+}
+
+func (r *RegistryService) publicKeyFingerprint(publicKeyPath string) (string, error) {
+	publicKey, err := r.keyLoader.LoadPublicKey(publicKeyPath)
+	if err != nil {
+		return "", err
+	}
+	return hybridcrypto.PublicKeyFingerprint(publicKey)
+}
@@ -1,85 +1,158 @@
 package service
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/url"
 	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
 	"rapid-bridge/domain/security"
 	"rapid-bridge/internal/adapter"
+	"rapid-bridge/internal/adapter/upstream"
 	"rapid-bridge/internal/dto/application"
 	"rapid-bridge/internal/dto/rapid"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/tracing"
 	"rapid-bridge/pkg/util"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 type RapidResourceService struct {
-	loader   port.KeyLoader
-	security security.Security
-	logger   port.Logger
-	config   port.ServerConfig
+	loader    port.KeyLoader
+	security  security.Security
+	upstreams port.UpstreamPool
+	clients   port.UpstreamClients
+	breakers  port.CircuitBreaker
+	logger    port.Logger
+	config    port.ServerConfig
 }
 
-func (r *RapidResourceService) HandleResource(c echo.Context, request application.ResourceRequest) (application.ResourceResponse, error) {
+func (r *RapidResourceService) HandleResource(c echo.Context, route port.Route, request application.ResourceRequest) (application.ResourceResponse, error) {
 
 	ctx := util.GetReqCtxFromEchoCtx(c)
+	logger := util.RequestLogger(ctx, r.logger)
 
 	from := ctx.Value(constants.From).(string)
 	to := ctx.Value(constants.To).(string)
 	keyVersion := ctx.Value(constants.KeyVersion).(string)
+	dataDir := r.config.GetDataDir()
 
-	rsaPrivateKeyPath := util.GetRSAPrivateKeyPath(from, keyVersion)
+	if !r.config.IsApplicationRegistered(from) {
+		logger.Error("Source application is not registered", zap.String("from", from))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeAppNotRegistered, fmt.Sprintf("application is not registered: %s", from))
+	}
+	if r.config.IsApplicationDisabled(from) {
+		logger.Error("Source application is disabled", zap.String("from", from))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeAppDisabled, fmt.Sprintf("application is disabled: %s", from))
+	}
+
+	rapidUrls, ok := r.config.GetBankRapidUrls(to)
+	if !ok {
+		logger.Error("No bank configured for destination", zap.String("to", to))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeBankNotFound, fmt.Sprintf("no bank configured for destination slug: %s", to))
+	}
+	if len(rapidUrls) == 0 {
+		logger.Error("No rapid links url configured for bank", zap.String("to", to))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeConfigError, fmt.Sprintf("no rapid links url configured for bank: %s", to))
+	}
+	if !route.AllowsBank(to) {
+		logger.Error("Route is not enabled for bank", zap.String("route", route.Method+" "+route.Path), zap.String("to", to))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeRouteNotEnabled, fmt.Sprintf("route %s %s is not enabled for bank: %s", route.Method, route.Path, to))
+	}
+
+	// only keys of the source application itself may be used
+	if !slices.Contains(util.ApplicationKeyVersions(dataDir, from), keyVersion) {
+		logger.Error("Unknown key version of source application", zap.String("from", from), zap.String("key_version", keyVersion))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeKeyVersionUnknown, fmt.Sprintf("application %s has no keys with version %s", from, keyVersion))
+	}
+
+	_, span := tracing.Start(ctx, "rapid.load_keys", trace.WithAttributes(attribute.String("rapid.key_version", keyVersion)))
+
+	rsaPrivateKeyPath := util.GetRSAPrivateKeyPath(dataDir, from, keyVersion)
 	rsaPrivateKey, err := r.loader.LoadPrivateKey(rsaPrivateKeyPath)
 
 	if err != nil {
-		r.logger.Error("Failed to read private keys", zap.String("error", err.Error()))
+		logger.Error("Failed to read private keys", zap.String("error", err.Error()))
+		tracing.End(span, err)
+		if stderrors.Is(err, fs.ErrNotExist) {
+			return application.ResourceResponse{}, errors.NewCodedError(errors.CodeKeyVersionUnknown, fmt.Sprintf("application %s has no keys with version %s", from, keyVersion))
+		}
 		return application.ResourceResponse{}, err
 	}
 
-	ed25519PrivateKey, err := r.loader.LoadPrivateKey(util.GetEd25519PrivateKeyPath(from, keyVersion))
+	ed25519PrivateKey, err := r.loader.LoadPrivateKey(util.GetEd25519PrivateKeyPath(dataDir, from, keyVersion))
 
 	if err != nil {
-		r.logger.Error("Failed to read private keys", zap.String("error", err.Error()))
+		logger.Error("Failed to read private keys", zap.String("error", err.Error()))
+		tracing.End(span, err)
 		return application.ResourceResponse{}, err
 	}
 
-	bankRsaPublicKey, err := r.loader.LoadPublicKey(util.GetBankRSAPublicKeyPath(to))
+	bankRsaPublicKey, err := r.loader.LoadPublicKey(util.GetBankRSAPublicKeyPath(dataDir, to))
 
 	if err != nil {
-		r.logger.Error("Failed to read public keys", zap.String("error", err.Error()))
-		return application.ResourceResponse{}, err
+		logger.Error("Failed to read public keys", zap.String("error", err.Error()))
+		tracing.End(span, err)
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeConfigError, fmt.Sprintf("public keys of bank %s could not be loaded", to))
 	}
 
-	bankEdPublicKey, err := r.loader.LoadPublicKey(util.GetBankEd25519PublicKeyPath(to))
+	bankEdPublicKey, err := r.loader.LoadPublicKey(util.GetBankEd25519PublicKeyPath(dataDir, to))
 
 	if err != nil {
-		r.logger.Error("Failed to read public keys", zap.String("error", err.Error()))
-		return application.ResourceResponse{}, err
+		logger.Error("Failed to read public keys", zap.String("error", err.Error()))
+		tracing.End(span, err)
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeConfigError, fmt.Sprintf("public keys of bank %s could not be loaded", to))
 	}
+	span.End()
+
+	_, span = tracing.Start(ctx, "rapid.encrypt")
 
 	// convert request struct to bytes
 	data, err := json.Marshal(request)
 	if err != nil {
-		r.logger.Error("Failed to marshal request", zap.String("error", err.Error()))
+		logger.Error("Failed to marshal request", zap.String("error", err.Error()))
+		tracing.End(span, err)
 		return application.ResourceResponse{}, err
 	}
 
 	ciphertext, encryptedAESKey, nonce, err := r.security.Encrypt(data, bankRsaPublicKey.(*rsa.PublicKey))
 	if err != nil {
-		r.logger.Error("Failed to encrypt payload", zap.String("error", err.Error()))
+		logger.Error("Failed to encrypt payload", zap.String("error", err.Error()))
+		tracing.End(span, err)
 		return application.ResourceResponse{}, err
 	}
 
-	// sign payload
-	signature, err := r.security.CreateDigitalSignature(ed25519PrivateKey.(ed25519.PrivateKey), ciphertext, encryptedAESKey, nonce)
-
 	// create base64 encrypted payload
 	base64EncryptedPayload, err := r.security.CreateBase64Encrypted(ciphertext, encryptedAESKey, nonce)
 	if err != nil {
-		r.logger.Error("Failed to create base64 encrypted payload", zap.String("error", err.Error()))
+		logger.Error("Failed to create base64 encrypted payload", zap.String("error", err.Error()))
+		tracing.End(span, err)
+		return application.ResourceResponse{}, err
+	}
+	span.End()
+
+	_, span = tracing.Start(ctx, "rapid.sign")
+
+	// sign payload
+	signature, err := r.security.CreateDigitalSignature(ed25519PrivateKey.(ed25519.PrivateKey), ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		logger.Error("Failed to sign payload", zap.String("error", err.Error()))
+		tracing.End(span, err)
 		return application.ResourceResponse{}, err
 	}
 
@@ -92,50 +165,243 @@ func (r *RapidResourceService) HandleResource(c echo.Context, request applicatio
 		KeyVersion: keyVersion,
 	}
 
-	// send rapid resource request to rapid links
-	rapidLinksUrl := r.config.GetRapidLinksUrl()
-	rapidResourceResponse, err := adapter.SendRequestToRapidLinks(r.logger, rapidLinksUrl, c.Request().URL.Path, rapidResourceRequest, c.Request().Header)
+	rapidResourceRequest.Metadata = &rapid.EnvelopeMetadata{
+		Method:         route.Method,
+		Path:           route.UpstreamPath,
+		QueryInPayload: len(request.Query) > 0,
+		RequestID:      util.GetRequestID(ctx),
+	}
+	signingBytes, err := rapidResourceRequest.Metadata.SigningBytes(rapidResourceRequest.Message)
+	if err == nil {
+		rapidResourceRequest.MetadataSignature, err = r.security.Sign(ed25519PrivateKey.(ed25519.PrivateKey), signingBytes)
+	}
+	tracing.End(span, err)
 	if err != nil {
-		r.logger.Error("Failed to send rapid resource request to rapid links", zap.String("error", err.Error()))
+		logger.Error("Failed to sign envelope metadata", zap.String("error", err.Error()))
 		return application.ResourceResponse{}, err
 	}
 
-	r.logger.Info("Message from rapid links", zap.String("from", rapidResourceResponse.Data.From), zap.String("to", rapidResourceResponse.Data.To))
+	// the route timeout covers every endpoint and retry
+	upstreamCtx, cancel := context.WithTimeout(c.Request().Context(), route.Timeout)
+	defer cancel()
 
-	// decode message and get ciphertext, encrypted aes key and nonce
-	ciphertext, encryptedAESKey, nonce, err = r.security.DecodeBase64Encrypted(rapidResourceResponse.Data.Message)
+	client, err := r.clients.Client(r.config.GetBankTLS(to))
 	if err != nil {
-		r.logger.Error("Failed to decode message", zap.String("error", err.Error()))
-		return application.ResourceResponse{}, err
+		logger.Error("Invalid upstream TLS configuration of bank", zap.String("to", to), zap.String("error", err.Error()))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeConfigError, fmt.Sprintf("invalid upstream tls configuration for bank: %s", to))
 	}
 
-	// decrypt payload
-	decryptedPayload, err := r.security.Decrypt(rsaPrivateKey.(*rsa.PrivateKey), ciphertext, encryptedAESKey, nonce)
+	if retryAfter, ok := r.breakers.Allow(to); !ok {
+		logger.Warn("Circuit open, not forwarding to bank", zap.String("to", to), zap.String("retry_after", retryAfter.String()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeUpstreamCircuitOpen, fmt.Sprintf("rapid links of bank %s is failing, retry later", to))
+	}
+
+	// only allowed application headers reach the bank
+	header := upstream.ForwardHeader(c.Request().Header, r.config.GetBankForwardHeaders(to))
+	if requestID := util.GetRequestID(ctx); requestID != "" {
+		header.Set(echo.HeaderXRequestID, requestID)
+	}
+
+	// covers every endpoint and retry, each attempt has a span of its own
+	upstreamCtx, span = tracing.Start(upstreamCtx, "rapid.upstream", trace.WithAttributes(attribute.String("rapid.to", to)))
+
+	var rapidResourceResponse rapid.RapidResourceResponse
+	for attempt := 0; ; attempt++ {
+		rapidResourceResponse, err = r.sendToBank(upstreamCtx, client, rapidUrls, route, rapidResourceRequest, header)
+		if err == nil || !upstream.IsConnectError(err) {
+			c.Set(constants.Forwarded, true)
+		}
+		if err == nil || attempt == constants.UpstreamRetries || !isRetryable(route, err) {
+			break
+		}
+
+		delay := upstream.Backoff(attempt, constants.UpstreamRetryBaseDelay*time.Millisecond, constants.UpstreamRetryMaxDelay*time.Millisecond)
+		logger.Warn("Rapid links request failed, retrying", zap.String("to", to), zap.Int("attempt", attempt+1), zap.String("delay", delay.String()), zap.String("error", err.Error()))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			continue
+		case <-upstreamCtx.Done():
+			timer.Stop()
+		}
+		break
+	}
+
+	switch {
+	case !isBankFailure(rapidResourceResponse, err):
+		r.breakers.RecordSuccess(to)
+	case c.Request().Context().Err() != nil:
+		// the caller went away, which says nothing about the bank
+	default:
+		r.breakers.RecordFailure(to)
+	}
+	tracing.End(span, err)
+
 	if err != nil {
-		r.logger.Error("Failed to decrypt payload", zap.String("error", err.Error()))
+		logger.Error("Failed to send rapid resource request to rapid links", zap.String("error", err.Error()))
+		err = upstreamError(upstreamCtx, c, route, to, err)
+		upstreamErrors.WithLabelValues(to, string(upstreamErrorCode(err))).Inc()
 		return application.ResourceResponse{}, err
 	}
 
+	logger.Info("Message from rapid links", zap.String("from", rapidResourceResponse.Data.From), zap.String("to", rapidResourceResponse.Data.To), zap.Int("status_code", rapidResourceResponse.StatusCode))
+	c.Response().Header().Set(constants.UpstreamStatus, strconv.Itoa(rapidResourceResponse.StatusCode))
+
+	// a response that cannot be opened is the bank's fault, not the caller's
+	invalidResponse := errors.NewCodedError(errors.CodeUpstreamInvalidResponse, fmt.Sprintf("invalid response from rapid links of bank %s", to))
+
 	// verify signature
+	_, span = tracing.Start(ctx, "rapid.verify")
 	err = r.security.VerifyDigitalSignature(rapidResourceResponse.Data.Message, rapidResourceResponse.Data.Signature, bankEdPublicKey.(ed25519.PublicKey))
+	tracing.End(span, err)
 	if err != nil {
-		r.logger.Error("Failed to verify digital signature", zap.String("error", err.Error()))
-		return application.ResourceResponse{}, err
+		logger.Error("Failed to verify digital signature", zap.String("error", err.Error()))
+		signatureFailures.WithLabelValues(to).Inc()
+		return application.ResourceResponse{}, errors.NewCodedError(errors.CodeSigInvalid, fmt.Sprintf("signature of the response from rapid links of bank %s does not verify", to))
+	}
+
+	_, span = tracing.Start(ctx, "rapid.decrypt")
+
+	// decode message and get ciphertext, encrypted aes key and nonce
+	ciphertext, encryptedAESKey, nonce, err = r.security.DecodeBase64Encrypted(rapidResourceResponse.Data.Message)
+	if err != nil {
+		logger.Error("Failed to decode message", zap.String("error", err.Error()))
+		tracing.End(span, err)
+		upstreamErrors.WithLabelValues(to, string(errors.CodeUpstreamInvalidResponse)).Inc()
+		return application.ResourceResponse{}, invalidResponse
+	}
+
+	// decrypt payload
+	decryptedPayload, err := r.security.Decrypt(rsaPrivateKey.(*rsa.PrivateKey), ciphertext, encryptedAESKey, nonce)
+	tracing.End(span, err)
+	if err != nil {
+		logger.Error("Failed to decrypt payload", zap.String("error", err.Error()))
+		upstreamErrors.WithLabelValues(to, string(errors.CodeUpstreamInvalidResponse)).Inc()
+		return application.ResourceResponse{}, invalidResponse
+	}
+
+	// the bank's status is passed on with its payload, except that its server
+	// errors become 502 so they are not taken for errors of the bridge
+	statusCode := rapidResourceResponse.StatusCode
+	if statusCode >= 500 || (rapidResourceResponse.Error && statusCode < 400) {
+		statusCode = http.StatusBadGateway
 	}
 
 	// create rapid resource response
 	applicationResponse := application.ResourceResponse{
-		Message: string(decryptedPayload),
+		Message:    string(decryptedPayload),
+		StatusCode: statusCode,
 	}
 
 	return applicationResponse, nil
 }
 
-func NewRapidResourceService(keyLoader port.KeyLoader, security security.Security, logger port.Logger, config port.ServerConfig) *RapidResourceService {
+// upstreamError maps an error of the exchange with the bank to the error the
+// caller gets.
+func upstreamError(upstreamCtx context.Context, c echo.Context, route port.Route, to string, err error) error {
+	if stderrors.Is(upstreamCtx.Err(), context.DeadlineExceeded) {
+		return errors.NewCodedError(errors.CodeUpstreamTimeout, fmt.Sprintf("rapid links did not answer within %s", route.Timeout))
+	}
+	var unavailable *adapter.UnavailableError
+	if stderrors.As(err, &unavailable) {
+		return errors.NewCodedError(errors.CodeUpstreamUnavailable, unavailable.Error())
+	}
+	var upstreamErr errors.UpstreamError
+	if stderrors.As(err, &upstreamErr) {
+		c.Response().Header().Set(constants.UpstreamStatus, strconv.Itoa(upstreamErr.UpstreamStatus))
+		return err
+	}
+	if stderrors.Is(err, adapter.ErrInvalidResponse) {
+		return errors.NewCodedError(errors.CodeUpstreamInvalidResponse, fmt.Sprintf("invalid response from rapid links of bank %s", to))
+	}
+	var urlErr *url.Error
+	if stderrors.As(err, &urlErr) {
+		return errors.NewCodedError(errors.CodeUpstreamUnavailable, fmt.Sprintf("rapid links of bank %s did not answer", to))
+	}
+	return err
+}
+
+// sendToBank sends the request to the bank's rapid links endpoints, healthy
+// ones first, failing over only when an endpoint could not be reached at all.
+func (r *RapidResourceService) sendToBank(ctx context.Context, client *http.Client, rapidUrls []string, route port.Route, request rapid.RapidResourceRequest, header http.Header) (rapid.RapidResourceResponse, error) {
+	var response rapid.RapidResourceResponse
+	var err error
+	logger := util.RequestLogger(ctx, r.logger)
+
+	for _, rapidLinksUrl := range r.upstreams.Order(rapidUrls) {
+		spanCtx, span := tracing.Start(ctx, route.Method+" "+route.UpstreamPath,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.URLFull(rapidLinksUrl+route.UpstreamPath)),
+		)
+
+		start := time.Now()
+		response, err = adapter.SendRequestToRapidLinks(spanCtx, client, r.logger, rapidLinksUrl, route.UpstreamPath, request, header)
+		upstreamDuration.WithLabelValues(request.To, upstreamOutcome(response, err)).Observe(time.Since(start).Seconds())
+
+		if response.StatusCode != 0 {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+		}
+		tracing.End(span, err)
+
+		if err == nil {
+			r.upstreams.MarkSuccess(rapidLinksUrl)
+			return response, nil
+		}
+
+		if !upstream.IsConnectError(err) {
+			return response, err
+		}
+
+		r.upstreams.MarkFailure(rapidLinksUrl, err)
+		logger.Warn("Rapid links endpoint unreachable, trying next", zap.String("url", rapidLinksUrl), zap.String("error", err.Error()))
+	}
+
+	return response, err
+}
+
+// isUpstreamFailure reports whether err means the bank could not be reached
+// or could not serve the request, as opposed to an answer it gave.
+func isUpstreamFailure(err error) bool {
+	var unavailable *adapter.UnavailableError
+	var urlErr *url.Error
+	return stderrors.As(err, &unavailable) || stderrors.As(err, &urlErr)
+}
+
+// isBankFailure reports whether the outcome of a request counts against the
+// bank's circuit: the bank could not be reached, answered with a server error
+// or answered something that is not a Rapid response. Client errors are
+// answers about the request and do not count.
+func isBankFailure(response rapid.RapidResourceResponse, err error) bool {
+	if err == nil {
+		return response.StatusCode >= 500
+	}
+	var upstreamErr errors.UpstreamError
+	if stderrors.As(err, &upstreamErr) {
+		return upstreamErr.UpstreamStatus >= 500
+	}
+	return isUpstreamFailure(err) || stderrors.Is(err, adapter.ErrInvalidResponse)
+}
+
+// isRetryable reports whether the request may be sent again. A request that
+// never reached the bank always may; one that may have reached it only when
+// the route is idempotent.
+func isRetryable(route port.Route, err error) bool {
+	if upstream.IsConnectError(err) {
+		return true
+	}
+	return route.Idempotent && isUpstreamFailure(err)
+}
+
+func NewRapidResourceService(keyLoader port.KeyLoader, security security.Security, upstreams port.UpstreamPool, clients port.UpstreamClients, breakers port.CircuitBreaker, logger port.Logger, config port.ServerConfig) *RapidResourceService {
 	return &RapidResourceService{
-		loader:   keyLoader,
-		security: security,
-		logger:   logger,
-		config:   config,
+		loader:    keyLoader,
+		security:  security,
+		upstreams: upstreams,
+		clients:   clients,
+		breakers:  breakers,
+		logger:    logger,
+		config:    config,
 	}
 }
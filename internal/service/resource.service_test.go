@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"rapid-bridge/internal/adapter"
+	"rapid-bridge/internal/adapter/logger"
+	"rapid-bridge/internal/adapter/upstream"
+	"rapid-bridge/internal/dto/rapid"
+	errors "rapid-bridge/internal/error"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsBankFailure(t *testing.T) {
+	const threshold = 3
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		err         error
+		wantFailure bool
+	}{
+		{"signed answer", 200, nil, false},
+		{"signed client error", 404, nil, false},
+		{"signed server error", 500, nil, true},
+		{"repeated 500s", 0, errors.UpstreamError{UpstreamStatus: 500, Code: "INTERNAL", Message: "internal error"}, true},
+		{"error page of a proxy", 0, errors.UpstreamError{UpstreamStatus: 502, Message: "Bad Gateway"}, true},
+		{"client error", 0, errors.UpstreamError{UpstreamStatus: 422, Code: "INSUFFICIENT-FUNDS", Message: "insufficient funds"}, false},
+		{"not a rapid response", 0, fmt.Errorf("%w: unexpected end of JSON input", adapter.ErrInvalidResponse), true},
+		{"unavailable", 0, &adapter.UnavailableError{StatusCode: 503}, true},
+		{"unreachable", 0, &url.Error{Op: "Post", URL: "http://bank", Err: syscall.ECONNREFUSED}, true},
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := rapid.RapidResourceResponse{StatusCode: tt.statusCode}
+			if got := isBankFailure(response, tt.err); got != tt.wantFailure {
+				t.Fatalf("isBankFailure = %v, want %v", got, tt.wantFailure)
+			}
+
+			// the same outcome threshold times in a row opens the circuit
+			// exactly when it is a failure
+			breakers := upstream.NewCircuitBreakers(zapLogger, threshold, time.Minute)
+			for range threshold {
+				if isBankFailure(response, tt.err) {
+					breakers.RecordFailure("bank")
+				} else {
+					breakers.RecordSuccess("bank")
+				}
+			}
+			wantState := upstream.CircuitClosed
+			if tt.wantFailure {
+				wantState = upstream.CircuitOpen
+			}
+			if state := breakers.State("bank"); state != wantState {
+				t.Fatalf("State = %s, want %s", state, wantState)
+			}
+		})
+	}
+}
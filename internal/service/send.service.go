@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/adapter"
+	"rapid-bridge/internal/adapter/upstream"
+	"rapid-bridge/internal/dto/rapid"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type SendResult struct {
+	StatusCode int           `json:"status_code"`
+	Duration   time.Duration `json:"duration"`
+	KeyVersion string        `json:"key_version"`
+	// the bridge response, or the raw Rapid Links response when sent directly
+	Body []byte `json:"-"`
+
+	// set when the request was sent directly to Rapid Links
+	RapidUrl        string                      `json:"rapid_url,omitempty"`
+	RequestEnvelope *rapid.RapidResourceRequest `json:"request_envelope,omitempty"`
+	Payload         []byte                      `json:"-"`
+	OpenError       string                      `json:"open_error,omitempty"`
+}
+
+// SendService sends application requests the way an integrating application
+// would, either through a running bridge or, for debugging, directly to the
+// bank's Rapid Links endpoints with the bridge's own envelope handling.
+type SendService struct {
+	envelopes *EnvelopeService
+	config    port.CLIConfig
+	logger    port.Logger
+}
+
+func NewSendService(envelopes *EnvelopeService, config port.CLIConfig, logger port.Logger) *SendService {
+	return &SendService{
+		envelopes: envelopes,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// ResourcePath returns the request path of a route given either as
+// "balance" or as the full "/api/v1/resource/balance". A query string is
+// kept.
+func ResourcePath(route string) string {
+	return constants.ResourcePathPrefix + strings.TrimPrefix(strings.TrimPrefix(route, constants.ResourcePathPrefix), "/")
+}
+
+// Send sends the plaintext body to the bridge with the routing headers and
+// the application's API key. GET and DELETE requests are sent without a body.
+func (s *SendService) Send(bridgeUrl, apiKey, method, route, from, to, keyVersion string, body []byte) (SendResult, error) {
+	keyVersion, err := resolveKeyVersion(s.config, from, keyVersion)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	if method == http.MethodGet || method == http.MethodDelete {
+		body = nil
+	}
+
+	header := s.routingHeader(from, to, keyVersion)
+	if apiKey != "" {
+		header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	response, err := adapter.SendJSON(method, strings.TrimSuffix(bridgeUrl, "/")+ResourcePath(route), header, body)
+	if err != nil {
+		s.logger.Error("Failed to reach the bridge", zap.String("url", bridgeUrl), zap.String("error", err.Error()))
+		return SendResult{}, err
+	}
+
+	return SendResult{
+		StatusCode: response.StatusCode,
+		Duration:   response.Duration,
+		KeyVersion: keyVersion,
+		Body:       response.Body,
+	}, nil
+}
+
+// SendRaw seals the body like the bridge does and posts the envelope to the
+// bank's Rapid URLs, keeping the upstream response as is. Opening the
+// response is attempted but a failure is reported, not returned, since
+// looking at a broken response is the point of a raw send.
+func (s *SendService) SendRaw(method, route, from, to, keyVersion string, body []byte) (SendResult, error) {
+	resourceUrl, err := url.Parse(ResourcePath(route))
+	if err != nil {
+		return SendResult{}, fmt.Errorf("invalid route %s: %w", route, err)
+	}
+
+	envelope, err := s.envelopes.SealRequest(from, to, keyVersion, method, resourceUrl.Path, resourceUrl.Query(), body)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	bankDetails, err := s.config.LoadBankDetails(to)
+	if err != nil {
+		return SendResult{}, err
+	}
+	if len(bankDetails.RapidUrls) == 0 {
+		return SendResult{}, fmt.Errorf("no rapid url configured for bank: %s", to)
+	}
+
+	client, err := upstream.NewHTTPClient(bankDetails.TLS)
+	if err != nil {
+		return SendResult{}, fmt.Errorf("invalid upstream tls configuration for bank %s: %w", to, err)
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	var response adapter.RawResponse
+	var rapidUrl string
+	for _, rapidUrl = range bankDetails.RapidUrls {
+		// the query string travels inside the envelope only
+		response, err = adapter.SendJSONWithClient(client, http.MethodPost, rapidUrl+resourceUrl.Path, s.routingHeader(from, to, envelope.KeyVersion), payload)
+		if err == nil || !upstream.IsConnectError(err) {
+			break
+		}
+		s.logger.Warn("Rapid links endpoint unreachable, trying next", zap.String("url", rapidUrl), zap.String("error", err.Error()))
+	}
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	result := SendResult{
+		StatusCode:      response.StatusCode,
+		Duration:        response.Duration,
+		KeyVersion:      envelope.KeyVersion,
+		Body:            response.Body,
+		RapidUrl:        rapidUrl,
+		RequestEnvelope: &envelope,
+	}
+
+	responseEnvelope, err := ParseEnvelope(response.Body)
+	if err == nil {
+		result.Payload, err = s.envelopes.Open(responseEnvelope)
+	}
+	if err != nil {
+		result.OpenError = err.Error()
+	}
+
+	return result, nil
+}
+
+func (s *SendService) routingHeader(from, to, keyVersion string) http.Header {
+	header := http.Header{}
+	header.Set(constants.From, from)
+	header.Set(constants.To, to)
+	header.Set(constants.KeyVersion, keyVersion)
+	return header
+}
@@ -1,6 +1,7 @@
 package setup
 
 import (
+	"fmt"
 	"log"
 	"rapid-bridge/domain/port"
 	"rapid-bridge/internal/adapter/config"
@@ -9,6 +10,8 @@ import (
 
 type Application struct {
 	Config port.ServerConfig
+	Routes port.RouteTable
+	Limits port.LimitsConfig
 	Logger port.Logger
 }
 
@@ -17,36 +20,69 @@ type CLIApplication struct {
 	Logger port.Logger
 }
 
-func NewApplication() *Application {
+func NewApplication(dataDir string) *Application {
+	app, err := LoadApplication(dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return app
+}
+
+// LoadApplication is NewApplication returning the error instead of exiting.
+func LoadApplication(dataDir string) (*Application, error) {
 	logger, err := logger.NewZapLogger()
 	if err != nil {
-		log.Fatalf("failed to initialize logger: %v", err)
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	cfg, err := config.LoadServerConfig(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	cfg, err := config.LoadServerConfig()
+	routes, err := config.LoadRouteTable(dataDir, logger)
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		return nil, fmt.Errorf("failed to load routes: %w", err)
+	}
+
+	limits, err := config.LoadLimits(dataDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load limits: %w", err)
 	}
 
 	return &Application{
 		Config: cfg,
+		Routes: routes,
+		Limits: limits,
 		Logger: logger,
+	}, nil
+}
+
+func NewCLIApplication(dataDir string) *CLIApplication {
+	app, err := LoadCLIApplication(dataDir)
+	if err != nil {
+		log.Fatal(err)
 	}
+
+	return app
 }
 
-func NewCLIApplication() *CLIApplication {
+// LoadCLIApplication is NewCLIApplication returning the error instead of
+// exiting, for callers that map failures to exit codes.
+func LoadCLIApplication(dataDir string) (*CLIApplication, error) {
 	logger, err := logger.NewZapLogger()
 	if err != nil {
-		log.Fatalf("failed to initialize logger: %v", err)
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
-	cfg, err := config.LoadCLIConfig()
+	cfg, err := config.LoadCLIConfig(dataDir)
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	return &CLIApplication{
 		Config: cfg,
 		Logger: logger,
-	}
+	}, nil
 }
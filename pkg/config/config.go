@@ -1,29 +1,106 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"rapid-bridge/constants"
+	"strings"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	ServerPort string `mapstructure:"SERVER_PORT"`
+	DataDir    string `mapstructure:"RAPID_BRIDGE_DATA_DIR"`
 }
 
+// DefaultConfigFile is the env file read when no --config flag is given.
+const DefaultConfigFile = ".env"
+
 func LoadConfig() (*Config, error) {
-	viper.SetConfigFile(".env")
-	viper.AutomaticEnv()
-	err := viper.ReadInConfig()
-	if err != nil {
-		fmt.Println("Can't find the file .env : ", err)
-		return nil, err
+	return LoadConfigFile(DefaultConfigFile, false)
+}
+
+// LoadConfigFile reads the settings from an env file, with environment
+// variables taking precedence. When optional is set a missing file is not an
+// error and only the environment is used.
+func LoadConfigFile(path string, optional bool) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("env")
+	v.AutomaticEnv()
+	v.BindEnv("SERVER_PORT")
+	v.BindEnv(constants.DataDirEnv)
+
+	if err := v.ReadInConfig(); err != nil {
+		if !optional || !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("can't read config file %s: %w", path, err)
+		}
 	}
 
 	var config Config
-	err = viper.Unmarshal(&config)
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("environment can't be loaded: %w", err)
+	}
+	return &config, nil
+}
+
+// ResolveDataDir returns the data directory to use. An explicit value (the
+// --data-dir flag) wins, followed by the RAPID_BRIDGE_DATA_DIR environment
+// variable, the same key in the env file and finally the built-in default.
+func ResolveDataDir(dataDir, configFile string) string {
+	if dataDir != "" {
+		return dataDir
+	}
+
+	if dataDir := os.Getenv(constants.DataDirEnv); dataDir != "" {
+		return dataDir
+	}
 
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	v.SetConfigType("env")
+	if err := v.ReadInConfig(); err == nil {
+		if dataDir := v.GetString(constants.DataDirEnv); dataDir != "" {
+			return dataDir
+		}
+	}
+
+	return constants.RapidBridgeData
+}
+
+// ResolveBridgeUrl returns the url of the local bridge for client commands.
+// An explicit value wins, otherwise it is built from SERVER_PORT in the
+// environment or the .env file.
+func ResolveBridgeUrl(bridgeUrl string) string {
+	if bridgeUrl != "" {
+		return bridgeUrl
+	}
+
+	serverPort := os.Getenv("SERVER_PORT")
+	if serverPort == "" {
+		v := viper.New()
+		v.SetConfigFile(DefaultConfigFile)
+		v.SetConfigType("env")
+		if err := v.ReadInConfig(); err == nil {
+			serverPort = v.GetString("SERVER_PORT")
+		}
+	}
+
+	host, port, err := net.SplitHostPort(serverPort)
 	if err != nil {
-		fmt.Println("Environment can't be loaded: ", err)
-		return nil, err
+		// SERVER_PORT=8080 is accepted as a bare port
+		host, port = "", strings.TrimPrefix(serverPort, ":")
 	}
-	return &config, nil
+	if port == "" {
+		port = "8080"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	return "http://" + net.JoinHostPort(host, port)
 }
@@ -0,0 +1,30 @@
+// Package metrics holds the Prometheus registry the bridge exposes.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry the bridge exposes. It is not the global registry
+// of client_golang, so that libraries cannot add metrics to it unasked.
+var Default = prometheus.NewRegistry()
+
+// Factory registers new metrics with Default.
+var Factory = promauto.With(Default)
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics of Default in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
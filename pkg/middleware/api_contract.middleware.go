@@ -2,8 +2,8 @@ package middleware
 
 import (
 	"fmt"
-	"net/http"
 	"rapid-bridge/constants"
+	errors "rapid-bridge/internal/error"
 	"rapid-bridge/pkg/util"
 
 	"github.com/labstack/echo/v4"
@@ -16,15 +16,26 @@ func APIContractMiddleware() echo.MiddlewareFunc {
 
 			from, _ := reqCtx.Value(constants.From).(string)
 			if from == "" {
-				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s not found in header", constants.From))
+				return errors.NewCodedError(errors.CodeHeaderMissing, fmt.Sprintf("%s not found in header", constants.From))
 			}
 			to, _ := reqCtx.Value(constants.To).(string)
 			if to == "" {
-				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s not found in header", constants.To))
+				return errors.NewCodedError(errors.CodeHeaderMissing, fmt.Sprintf("%s not found in header", constants.To))
 			}
 			keyVersion, _ := reqCtx.Value(constants.KeyVersion).(string)
 			if keyVersion == "" {
-				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s not found in header", constants.KeyVersion))
+				return errors.NewCodedError(errors.CodeHeaderMissing, fmt.Sprintf("%s not found in header", constants.KeyVersion))
+			}
+
+			// the headers name directories of the data directory, and must not
+			// lead out of the caller's own
+			for _, header := range []string{constants.From, constants.To} {
+				if !util.IsPathElement(c.Request().Header.Get(header)) {
+					return errors.NewCodedError(errors.CodeInvalidRequest, fmt.Sprintf("%s is not a valid slug", header))
+				}
+			}
+			if !util.IsKeyVersion(keyVersion) {
+				return errors.NewCodedError(errors.CodeKeyVersionUnknown, fmt.Sprintf("%s must be a key version (ULID)", constants.KeyVersion))
 			}
 
 			err := next(c)
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rapid-bridge/constants"
+	"rapid-bridge/internal/adapter/logger"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/internal/handler"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+const testKeyVersion = "01M59ZXYWS3P1PF2NDVFGS92HX"
+
+func TestAPIContractMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       string
+		to         string
+		keyVersion string
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{"valid", "app", "bank", testKeyVersion, http.StatusOK, ""},
+		{"missing source", "", "bank", testKeyVersion, http.StatusBadRequest, errors.CodeHeaderMissing},
+		{"missing destination", "app", "", testKeyVersion, http.StatusBadRequest, errors.CodeHeaderMissing},
+		{"missing key version", "app", "bank", "", http.StatusBadRequest, errors.CodeHeaderMissing},
+		{"key version traversal", "attacker", "bank", "../victim/" + testKeyVersion, http.StatusBadRequest, errors.CodeKeyVersionUnknown},
+		{"key version with separator", "app", "bank", testKeyVersion + "/x", http.StatusBadRequest, errors.CodeKeyVersionUnknown},
+		{"key version not a ulid", "app", "bank", "v1", http.StatusBadRequest, errors.CodeKeyVersionUnknown},
+		{"key version lower case", "app", "bank", "01m59zxyws3p1pf2ndvfgs92hx", http.StatusBadRequest, errors.CodeKeyVersionUnknown},
+		{"source traversal", "../application/victim", "bank", testKeyVersion, http.StatusBadRequest, errors.CodeInvalidRequest},
+		{"source dot dot", "..", "bank", testKeyVersion, http.StatusBadRequest, errors.CodeInvalidRequest},
+		{"source backslash", `app\x`, "bank", testKeyVersion, http.StatusBadRequest, errors.CodeInvalidRequest},
+		{"destination traversal", "app", "../../etc", testKeyVersion, http.StatusBadRequest, errors.CodeInvalidRequest},
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = handler.NewErrorHandler(zapLogger)
+			e.POST("/api/v1/resource/*", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, APIContractMiddleware())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/resource/balance", nil)
+			req.Header.Set(constants.From, tt.from)
+			req.Header.Set(constants.To, tt.to)
+			req.Header.Set(constants.KeyVersion, tt.keyVersion)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+
+			var problem struct {
+				Code errors.Code `json:"code"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("response is not a problem: %v: %s", err, rec.Body)
+			}
+			if problem.Code != tt.wantCode {
+				t.Fatalf("code = %s, want %s", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
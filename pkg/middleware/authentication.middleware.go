@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"fmt"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/util"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// APIKeyMiddleware requires an "Authorization: Bearer <api key>" header
+// holding a key of the application named in X-Source-Slug, so that a caller
+// can only use the keys of the application it authenticated as.
+func APIKeyMiddleware(authenticator port.ApplicationAuthenticator, logger port.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := util.RequestLogger(c.Request().Context(), logger)
+
+			from := c.Request().Header.Get(constants.From)
+
+			scheme, apiKey, _ := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !strings.EqualFold(scheme, "Bearer") || apiKey == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="rapid-bridge"`)
+				return errors.NewCodedError(errors.CodeAuthRequired, "api key required")
+			}
+
+			if err := authenticator.AuthenticateAPIKey(from, strings.TrimSpace(apiKey)); err != nil {
+				logger.Warn("Authentication failed", zap.String("from", from), zap.String("error", err.Error()))
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="rapid-bridge", error="invalid_token"`)
+				// an expired key is worth telling its holder about, other reasons
+				// are only logged
+				if code, ok := errors.CodeOf(err); ok && code == errors.CodeKeyExpired {
+					return errors.NewCodedError(errors.CodeKeyExpired, "api key of "+from+" has expired")
+				}
+				return errors.NewCodedError(errors.CodeAuthInvalid, "invalid api key for "+from)
+			}
+
+			// the credential is for the bridge only and must not reach the bank
+			c.Request().Header.Del(echo.HeaderAuthorization)
+
+			return next(c)
+		}
+	}
+}
+
+// ClientCertificateMiddleware authenticates callers by their verified client
+// certificate. X-Source-Slug is filled in from the certificate when it is
+// missing, and a request naming another application is rejected.
+func ClientCertificateMiddleware(resolver port.ClientIdentityResolver, logger port.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := util.RequestLogger(c.Request().Context(), logger)
+
+			state := c.Request().TLS
+			if state == nil || len(state.VerifiedChains) == 0 {
+				return errors.NewCodedError(errors.CodeCertRequired, "client certificate required")
+			}
+			certificate := state.VerifiedChains[0][0]
+
+			applicationSlug, ok := resolver.ApplicationForCertificate(certificate)
+			if !ok {
+				logger.Warn("Client certificate not mapped to an application", zap.String("subject", certificate.Subject.String()))
+				return errors.NewCodedError(errors.CodeCertUnmapped, "client certificate is not mapped to an application")
+			}
+
+			from := c.Request().Header.Get(constants.From)
+			if from == "" {
+				c.Request().Header.Set(constants.From, applicationSlug)
+			} else if from != applicationSlug {
+				logger.Warn("Source slug does not match the client certificate", zap.String("from", from), zap.String("certificate_application", applicationSlug))
+				return errors.NewCodedError(errors.CodeSourceMismatch, fmt.Sprintf("%s %s does not match the client certificate", constants.From, from))
+			}
+
+			return next(c)
+		}
+	}
+}
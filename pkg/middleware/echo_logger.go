@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"rapid-bridge/constants"
 	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/util"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -18,7 +20,7 @@ func CreateEchoLogger(logger port.Logger) echo.MiddlewareFunc {
 		LogLatency:      true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
 			logger.Info("request",
-				// "request_id", c.Get(constants.RequestId),
+				constants.RequestId, util.GetRequestID(c.Request().Context()),
 				"method", v.Method,
 				"host", v.Host,
 				"uri", v.URI,
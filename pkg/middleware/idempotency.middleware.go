@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/util"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// IdempotentReplayed marks a response that was answered from the idempotency
+// store instead of being forwarded again.
+const IdempotentReplayed = "Idempotent-Replayed"
+
+// response headers kept with a stored response
+var replayedHeaders = []string{echo.HeaderContentType, constants.UpstreamStatus}
+
+// IdempotencyMiddleware remembers for window the response to a request with an
+// Idempotency-Key header, and answers a repeated request with it instead of
+// forwarding it again. Keys are scoped to the source application. Only
+// requests that may have reached the bank are remembered; for the others the
+// key is released so that the request can be retried with it.
+func IdempotencyMiddleware(store port.IdempotencyStore, window time.Duration, logger port.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := util.RequestLogger(c.Request().Context(), logger)
+
+			key := c.Request().Header.Get(constants.IdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > constants.IdempotencyMaxKeyLength {
+				return errors.NewCodedError(errors.CodeInvalidRequest, fmt.Sprintf("%s must be at most %d characters", constants.IdempotencyKey, constants.IdempotencyMaxKeyLength))
+			}
+			from := c.Request().Header.Get(constants.From)
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return errors.NewCodedError(errors.CodeInvalidRequest, "request body could not be read")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			pending := port.IdempotencyRecord{
+				Fingerprint: fingerprint(c.Request(), body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(constants.IdempotencyPendingTimeout * time.Minute),
+			}
+			record, found, err := store.Reserve(from, key, pending)
+			if err != nil {
+				logger.Error("Failed to reserve idempotency key", zap.String("from", from), zap.String("error", err.Error()))
+				return err
+			}
+			if found {
+				return replay(c, record, pending.Fingerprint)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			if err := next(c); err != nil {
+				// rendered here, so that an error response is recorded too
+				c.Error(err)
+			}
+
+			if forwarded, _ := c.Get(constants.Forwarded).(bool); !forwarded {
+				if err := store.Release(from, key); err != nil {
+					logger.Error("Failed to release idempotency key", zap.String("from", from), zap.String("error", err.Error()))
+				}
+				return nil
+			}
+
+			completed := port.IdempotencyRecord{
+				Fingerprint: pending.Fingerprint,
+				CreatedAt:   now,
+				ExpiresAt:   time.Now().Add(window),
+				Completed:   true,
+				StatusCode:  c.Response().Status,
+				Header:      map[string]string{},
+				Body:        recorder.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := c.Response().Header().Get(name); value != "" {
+					completed.Header[name] = value
+				}
+			}
+			if err := store.Complete(from, key, completed); err != nil {
+				logger.Error("Failed to store idempotent response", zap.String("from", from), zap.String("error", err.Error()))
+			}
+			return nil
+		}
+	}
+}
+
+func replay(c echo.Context, record port.IdempotencyRecord, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return errors.NewCodedError(errors.CodeIdempotencyKeyReused, fmt.Sprintf("%s was already used for a different request", constants.IdempotencyKey))
+	}
+	if !record.Completed {
+		return errors.NewCodedError(errors.CodeIdempotencyInProgress, fmt.Sprintf("a request with this %s is still in progress", constants.IdempotencyKey))
+	}
+
+	for name, value := range record.Header {
+		c.Response().Header().Set(name, value)
+	}
+	c.Response().Header().Set(IdempotentReplayed, "true")
+	c.Response().WriteHeader(record.StatusCode)
+	_, err := c.Response().Write(record.Body)
+	return err
+}
+
+// fingerprint identifies what a request asks for, so that a key cannot be
+// reused for another one.
+func fingerprint(request *http.Request, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{request.Method, request.URL.Path, request.URL.RawQuery, request.Header.Get(constants.To)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{'\n'})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body as it is written.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/metrics"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var requestsTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+	Name: "rapid_bridge_requests_total",
+	Help: "Resource requests answered, by route, application, bank and status.",
+}, []string{"route", "application", "bank", "status"})
+
+var requestDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rapid_bridge_request_duration_seconds",
+	Help:    "Time taken to answer resource requests.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "application", "bank", "status"})
+
+var inFlightRequests = metrics.Factory.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "rapid_bridge_in_flight_requests",
+	Help: "Resource requests being handled.",
+}, []string{"route", "application", "bank"})
+
+// label values of requests for no known route, application or bank, which
+// keep arbitrary header values out of the metrics
+const (
+	unmatchedRoute = "unmatched"
+	unknownLabel   = "unknown"
+)
+
+// MetricsMiddleware counts resource requests and times them. It comes first,
+// so that requests refused by the other middlewares are counted too.
+func MetricsMiddleware(routes port.RouteTable, config port.ServerConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			route := unmatchedRoute
+			if matched, ok := matchRoute(c, routes); ok {
+				route = matched.Path
+			}
+			application := applicationLabel(c, config)
+			bank := bankLabel(c, config)
+
+			inFlight := inFlightRequests.WithLabelValues(route, application, bank)
+			inFlight.Inc()
+			err := next(c)
+			inFlight.Dec()
+			if err != nil {
+				// rendered here rather than after this middleware, so that
+				// the status is known
+				c.Error(err)
+			}
+
+			status := strconv.Itoa(c.Response().Status)
+			// with --auth mtls the application is only known by now
+			application = applicationLabel(c, config)
+
+			requestsTotal.WithLabelValues(route, application, bank, status).Inc()
+			requestDuration.WithLabelValues(route, application, bank, status).Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+	}
+}
+
+func applicationLabel(c echo.Context, config port.ServerConfig) string {
+	application := c.Request().Header.Get(constants.From)
+	if !config.IsApplicationRegistered(application) {
+		return unknownLabel
+	}
+	return application
+}
+
+func bankLabel(c echo.Context, config port.ServerConfig) string {
+	bank := c.Request().Header.Get(constants.To)
+	if _, ok := config.GetBankRapidUrls(bank); !ok {
+		return unknownLabel
+	}
+	return bank
+}
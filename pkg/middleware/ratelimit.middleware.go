@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	errors "rapid-bridge/internal/error"
+	"rapid-bridge/pkg/metrics"
+	"rapid-bridge/pkg/util"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var limitRefusals = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+	Name: "rapid_bridge_limit_refused_total",
+	Help: "Resource requests refused by a rate limit or concurrency cap.",
+}, []string{"scope", "name", "reason"})
+
+// RateLimitMiddleware counts each resource request against its application,
+// its destination bank and its route, and refuses it with 429 and
+// Retry-After when one of them is over its limit. It runs after
+// authentication, so that a caller cannot use up another application's
+// limit, and before any key is loaded. Applications and banks that are not
+// registered all count against the one name "unknown", so that made-up slugs
+// neither get a limit of their own nor grow the limiter and the metrics.
+func RateLimitMiddleware(limiter port.RateLimiter, routes port.RouteTable, config port.ServerConfig, logger port.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := util.RequestLogger(c.Request().Context(), logger)
+
+			keys := []port.LimitKey{
+				{Scope: port.LimitScopeApplication, Name: applicationLabel(c, config)},
+				{Scope: port.LimitScopeBank, Name: bankLabel(c, config)},
+			}
+			if route, ok := matchRoute(c, routes); ok {
+				keys = append(keys, port.LimitKey{Scope: port.LimitScopeRoute, Name: route.Path})
+			}
+
+			release, refusal := limiter.Acquire(keys)
+			if refusal != nil {
+				limitRefusals.WithLabelValues(refusal.Key.Scope, refusal.Key.Name, refusal.Reason).Inc()
+				logger.Warn("Request refused by limit", zap.String("scope", refusal.Key.Scope), zap.String("name", refusal.Key.Name), zap.String("reason", refusal.Reason), zap.String("retry_after", refusal.RetryAfter.String()))
+
+				retryAfter := max(1, int(math.Ceil(refusal.RetryAfter.Seconds())))
+				c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+				code := errors.CodeRateLimited
+				if refusal.Reason == port.LimitReasonConcurrency {
+					code = errors.CodeConcurrencyLimited
+				}
+				return errors.NewCodedError(code, fmt.Sprintf("%s %s is over its %s limit", refusal.Key.Scope, refusal.Key.Name, refusal.Reason))
+			}
+			defer release()
+
+			return next(c)
+		}
+	}
+}
+
+// matchRoute finds the route of a resource request, as the resource handler
+// does.
+func matchRoute(c echo.Context, routes port.RouteTable) (port.Route, bool) {
+	path := strings.TrimPrefix(c.Request().URL.Path, strings.TrimSuffix(constants.ResourcePathPrefix, "/"))
+	return routes.Match(c.Request().Method, path)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/internal/adapter/logger"
+	"rapid-bridge/internal/adapter/ratelimit"
+	"rapid-bridge/internal/handler"
+	"slices"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// serverConfig registers the application "app" and the bank "bank".
+type serverConfig struct {
+	port.ServerConfig
+}
+
+func (serverConfig) GetBankRapidUrls(bankSlug string) ([]string, bool) {
+	return []string{"http://localhost:9000"}, bankSlug == "bank"
+}
+
+func (serverConfig) IsApplicationRegistered(applicationSlug string) bool {
+	return applicationSlug == "app"
+}
+
+type noRoutes struct{}
+
+func (noRoutes) Match(method, path string) (port.Route, bool) { return port.Route{}, false }
+func (noRoutes) Methods(path string) []string                 { return nil }
+
+// defaultLimits limits every application and bank to one request at a time.
+type defaultLimits struct{}
+
+func (defaultLimits) Limit(scope, name string) (port.Limit, bool) {
+	return port.Limit{Concurrency: 1}, slices.Contains([]string{port.LimitScopeApplication, port.LimitScopeBank}, scope)
+}
+
+func TestRateLimitMiddlewareUnknownSlugs(t *testing.T) {
+	tests := []struct {
+		name string
+		// the request in flight, then the one sent next
+		held, next [2]string
+		wantStatus int
+		wantName   string
+	}{
+		{"registered", [2]string{"app", "bank"}, [2]string{"app", "bank"}, http.StatusTooManyRequests, "app"},
+		{"made-up banks share one limit", [2]string{"app", "bank-1"}, [2]string{"other", "bank-2"}, http.StatusTooManyRequests, "unknown"},
+		{"made-up applications share one limit", [2]string{"app-1", "bank"}, [2]string{"app-2", "other"}, http.StatusTooManyRequests, "unknown"},
+		{"made-up bank does not use a registered one's limit", [2]string{"other", "bank"}, [2]string{"app", "bank-2"}, http.StatusOK, ""},
+	}
+
+	zapLogger, err := logger.NewZapLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := ratelimit.NewLimiters(defaultLimits{})
+			release := make(chan struct{})
+			entered := make(chan struct{})
+
+			e := echo.New()
+			e.HTTPErrorHandler = handler.NewErrorHandler(zapLogger)
+			e.POST("/api/v1/resource/*", func(c echo.Context) error {
+				if c.Request().Header.Get("X-Hold") != "" {
+					close(entered)
+					<-release
+				}
+				return c.NoContent(http.StatusOK)
+			}, RateLimitMiddleware(limiter, noRoutes{}, serverConfig{}, zapLogger))
+
+			send := func(slugs [2]string, hold bool) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/resource/balance", nil)
+				req.Header.Set(constants.From, slugs[0])
+				req.Header.Set(constants.To, slugs[1])
+				if hold {
+					req.Header.Set("X-Hold", "1")
+				}
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				return rec
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				send(tt.held, true)
+			}()
+			<-entered
+
+			refused := func() float64 {
+				return testutil.ToFloat64(limitRefusals.WithLabelValues(port.LimitScopeBank, tt.wantName, port.LimitReasonConcurrency)) +
+					testutil.ToFloat64(limitRefusals.WithLabelValues(port.LimitScopeApplication, tt.wantName, port.LimitReasonConcurrency))
+			}
+			before := refused()
+			rec := send(tt.next, false)
+			close(release)
+			<-done
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantName != "" && refused() != before+1 {
+				t.Fatalf("refusal not counted under the name %q", tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"rapid-bridge/constants"
+	"rapid-bridge/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// RequestIDMiddleware gives every request an id, the X-Request-ID the caller
+// sent or a new ULID. The id is put in the request context and the
+// X-Request-ID request and response headers, so that the caller, the logs and
+// Rapid Links can all refer to the same request.
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = ulid.Make().String()
+			}
+
+			req.Header.Set(echo.HeaderXRequestID, requestID)
+			c.SetRequest(req.WithContext(util.WithRequestID(req.Context(), requestID)))
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			return next(c)
+		}
+	}
+}
+
+// validRequestID keeps ids that could break a log line or a header out.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > constants.RequestIDMaxLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
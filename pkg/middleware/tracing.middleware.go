@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/tracing"
+	"rapid-bridge/pkg/util"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts the server span of each request, continuing the
+// trace of an incoming traceparent. It comes right after the request id, so
+// that the span covers everything else the bridge does.
+func TracingMiddleware(routes port.RouteTable) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			// resource requests are named after their route in the route
+			// table, which echo only knows as a wildcard
+			route := c.Path()
+			if matched, ok := matchRoute(c, routes); ok && strings.HasPrefix(req.URL.Path, constants.ResourcePathPrefix) {
+				route = strings.TrimSuffix(constants.ResourcePathPrefix, "/") + matched.Path
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracing.Start(ctx, strings.TrimSpace(req.Method+" "+route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(req.URL.Path),
+					attribute.String(constants.RequestId, util.GetRequestID(ctx)),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				// rendered here, so that the span has the status
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if source := c.Request().Header.Get(constants.From); source != "" {
+				span.SetAttributes(attribute.String("rapid.from", source), attribute.String("rapid.to", c.Request().Header.Get(constants.To)))
+			}
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return nil
+		}
+	}
+}
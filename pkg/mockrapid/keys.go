@@ -0,0 +1,139 @@
+package mockrapid
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rapid-bridge/constants"
+	keymanagementfs "rapid-bridge/internal/adapter/keymanagement_fs"
+	hybridcrypto "rapid-bridge/pkg/security/crypto"
+	"rapid-bridge/pkg/util"
+)
+
+// BankKeys are the simulated bank's private keys. Their public halves are
+// what the bridge fetches from /public-key.
+type BankKeys struct {
+	RSAPrivateKey     *rsa.PrivateKey
+	Ed25519PrivateKey ed25519.PrivateKey
+}
+
+func GenerateBankKeys() (*BankKeys, error) {
+	rsaPrivateKey, _, err := hybridcrypto.GenerateRSAKeyPair(constants.RSAKeyBitSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ed25519PrivateKey, _, err := hybridcrypto.GenerateEd25519KeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	return &BankKeys{RSAPrivateKey: rsaPrivateKey, Ed25519PrivateKey: ed25519PrivateKey}, nil
+}
+
+// LoadOrGenerateBankKeys loads the bank keys from dir, generating and saving
+// them on first use so that keys fetched by a bridge stay valid across
+// restarts of the simulator.
+func LoadOrGenerateBankKeys(dir string) (*BankKeys, error) {
+	rsaPrivateKeyPath := filepath.Join(dir, constants.RSAPrivateKeyFile)
+	ed25519PrivateKeyPath := filepath.Join(dir, constants.Ed25519PrivateKeyFile)
+
+	if !util.FileExists(rsaPrivateKeyPath) && !util.FileExists(ed25519PrivateKeyPath) {
+		keys, err := GenerateBankKeys()
+		if err != nil {
+			return nil, err
+		}
+		if err := saveBankKeys(dir, keys); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+
+	keyLoader := keymanagementfs.NewFSKeyLoader()
+
+	rsaPrivateKey, err := keyLoader.LoadPrivateKey(rsaPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	ed25519PrivateKey, err := keyLoader.LoadPrivateKey(ed25519PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &BankKeys{}
+	var ok bool
+	if keys.RSAPrivateKey, ok = rsaPrivateKey.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("%s is not an RSA private key", rsaPrivateKeyPath)
+	}
+	if keys.Ed25519PrivateKey, ok = ed25519PrivateKey.(ed25519.PrivateKey); !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", ed25519PrivateKeyPath)
+	}
+
+	return keys, nil
+}
+
+func saveBankKeys(dir string, keys *BankKeys) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	keySaver := keymanagementfs.NewFSKeySaver()
+
+	if err := keySaver.SaveRSAPrivateKeyToPEM(keys.RSAPrivateKey, filepath.Join(dir, constants.RSAPrivateKeyFile)); err != nil {
+		return err
+	}
+	if err := keySaver.SaveRSAPublicKeyToPEM(&keys.RSAPrivateKey.PublicKey, filepath.Join(dir, constants.RSAPublicKeyFile)); err != nil {
+		return err
+	}
+	if err := keySaver.SaveEd25519PrivateKeyToPEM(keys.Ed25519PrivateKey, filepath.Join(dir, constants.Ed25519PrivateKeyFile)); err != nil {
+		return err
+	}
+	return keySaver.SaveEd25519PublicKeyToPEM(keys.Ed25519PrivateKey.Public().(ed25519.PublicKey), filepath.Join(dir, constants.Ed25519PublicKeyFile))
+}
+
+// ApplicationKeyResolver returns the public keys of the application that
+// sent a request: its Ed25519 key verifies the request and its RSA key
+// encrypts the response.
+type ApplicationKeyResolver interface {
+	ApplicationKeys(applicationSlug, keyVersion string) (*rsa.PublicKey, ed25519.PublicKey, error)
+}
+
+// ApplicationKeysFunc adapts a function to ApplicationKeyResolver.
+type ApplicationKeysFunc func(applicationSlug, keyVersion string) (*rsa.PublicKey, ed25519.PublicKey, error)
+
+func (f ApplicationKeysFunc) ApplicationKeys(applicationSlug, keyVersion string) (*rsa.PublicKey, ed25519.PublicKey, error) {
+	return f(applicationSlug, keyVersion)
+}
+
+// DataDirApplicationKeys reads application public keys from a bridge data
+// directory, which is how a simulator next to a bridge learns about the
+// applications registered with it.
+type DataDirApplicationKeys struct {
+	DataDir string
+}
+
+func (d DataDirApplicationKeys) ApplicationKeys(applicationSlug, keyVersion string) (*rsa.PublicKey, ed25519.PublicKey, error) {
+	keyLoader := keymanagementfs.NewFSKeyLoader()
+
+	rsaPublicKey, err := keyLoader.LoadPublicKey(util.GetRSAPublicKeyPath(d.DataDir, applicationSlug, keyVersion))
+	if err != nil {
+		return nil, nil, err
+	}
+	ed25519PublicKey, err := keyLoader.LoadPublicKey(util.GetEd25519PublicKeyPath(d.DataDir, applicationSlug, keyVersion))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applicationRsaPublicKey, ok := rsaPublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("application %s RSA public key is not an RSA key", applicationSlug)
+	}
+	applicationEd25519PublicKey, ok := ed25519PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("application %s Ed25519 public key is not an Ed25519 key", applicationSlug)
+	}
+
+	return applicationRsaPublicKey, applicationEd25519PublicKey, nil
+}
@@ -0,0 +1,345 @@
+// Package mockrapid is a local stand-in for a Rapid Links deployment. It
+// serves the bank public keys and the resource routes the bridge forwards
+// to, verifies and decrypts incoming envelopes like a bank would and answers
+// with scripted, properly encrypted and signed responses.
+//
+// The handler can be mounted in an httptest.Server for end to end tests, or
+// served with the mock-rapid command during development.
+package mockrapid
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/domain/security"
+	keymanagementfs "rapid-bridge/internal/adapter/keymanagement_fs"
+	securityadapter "rapid-bridge/internal/adapter/security"
+	"rapid-bridge/internal/dto/rapid"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ResourcePrefix is the path prefix of the routes forwarded by the bridge.
+// Requests are matched to scenarios by the rest of the path.
+const ResourcePrefix = constants.ResourcePathPrefix
+
+// Envelope is the wire format of a Rapid request, and of the "data" field of
+// a Rapid response.
+type Envelope struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Message    string `json:"message"`
+	Signature  string `json:"signature"`
+	KeyVersion string `json:"key_version"`
+
+	Metadata          *rapid.EnvelopeMetadata `json:"metadata,omitempty"`
+	MetadataSignature string                  `json:"metadata_signature,omitempty"`
+}
+
+type responseBody struct {
+	Data    *Envelope `json:"data,omitempty"`
+	Error   bool      `json:"error"`
+	Code    string    `json:"code,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// ReceivedRequest is a request the simulator accepted, with its decrypted
+// payload.
+type ReceivedRequest struct {
+	Route string `json:"route"`
+	// the application's method from the envelope metadata, POST without it
+	Method string `json:"method"`
+	// the bridge's request id from the envelope metadata
+	RequestID  string          `json:"request_id,omitempty"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	KeyVersion string          `json:"key_version"`
+	Payload    json.RawMessage `json:"payload"`
+	Header     http.Header     `json:"header"`
+}
+
+type Config struct {
+	Keys         *BankKeys
+	Applications ApplicationKeyResolver
+	// DefaultScenarios when nil
+	Scenarios Scenarios
+	Logger    port.Logger
+}
+
+type Server struct {
+	keys         *BankKeys
+	applications ApplicationKeyResolver
+	security     security.Security
+	logger       port.Logger
+	echo         *echo.Echo
+
+	mu        sync.Mutex
+	scenarios Scenarios
+	received  []ReceivedRequest
+	random    *rand.Rand
+}
+
+func New(config Config) (*Server, error) {
+	if config.Keys == nil {
+		return nil, errors.New("mockrapid: bank keys are required")
+	}
+	if config.Applications == nil {
+		return nil, errors.New("mockrapid: an application key resolver is required")
+	}
+	if config.Logger == nil {
+		return nil, errors.New("mockrapid: a logger is required")
+	}
+
+	scenarios := config.Scenarios
+	if scenarios == nil {
+		scenarios = DefaultScenarios()
+	}
+
+	s := &Server{
+		keys:         config.Keys,
+		applications: config.Applications,
+		security:     *security.NewSecurity(securityadapter.NewHybridCryptography()),
+		logger:       config.Logger,
+		scenarios:    scenarios,
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.GET("/public-key", s.handlePublicKey)
+	e.POST("/*", s.handleResource)
+	s.echo = e
+
+	return s, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.echo
+}
+
+func (s *Server) Start(address string) error {
+	return s.echo.Start(address)
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.echo.Shutdown(ctx)
+}
+
+// SetScenario replaces the scenario of a route, also while serving.
+func (s *Server) SetScenario(route string, scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios[route] = scenario
+}
+
+// Received returns the requests accepted so far, oldest first.
+func (s *Server) Received() []ReceivedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReceivedRequest(nil), s.received...)
+}
+
+// PublicKeys returns the bank public keys as served on /public-key: base64
+// encoded PKIX DER.
+func (s *Server) PublicKeys() (string, string, error) {
+	keyConverter := keymanagementfs.NewFSKeyConverter()
+
+	rsaPublicKey, err := keyConverter.ConvertPublicKeyToBase64(&s.keys.RSAPrivateKey.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	ed25519PublicKey, err := keyConverter.ConvertPublicKeyToBase64(s.keys.Ed25519PrivateKey.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	return rsaPublicKey, ed25519PublicKey, nil
+}
+
+func (s *Server) handlePublicKey(c echo.Context) error {
+	rsaPublicKey, ed25519PublicKey, err := s.PublicKeys()
+	if err != nil {
+		s.logger.Error("Failed to encode public keys", zap.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, responseBody{Error: true, Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]string{
+			"rsaPublicKey":     rsaPublicKey,
+			"ed25519PublicKey": ed25519PublicKey,
+		},
+	})
+}
+
+func (s *Server) handleResource(c echo.Context) error {
+	route := strings.TrimPrefix(strings.TrimPrefix(c.Request().URL.Path, ResourcePrefix), "/")
+
+	s.mu.Lock()
+	scenario, ok := s.scenarios[route]
+	faulty := scenario.Fault != FaultNone && (scenario.FaultRate <= 0 || s.random.Float64() < scenario.FaultRate)
+	s.mu.Unlock()
+
+	if !ok {
+		return c.JSON(http.StatusNotFound, responseBody{Error: true, Message: "no scenario for route: " + route})
+	}
+
+	if scenario.Latency > 0 {
+		select {
+		case <-time.After(time.Duration(scenario.Latency)):
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+
+	envelope := Envelope{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&envelope); err != nil {
+		return c.JSON(http.StatusBadRequest, responseBody{Error: true, Message: "invalid envelope: " + err.Error()})
+	}
+
+	applicationRsaPublicKey, applicationEd25519PublicKey, err := s.applications.ApplicationKeys(envelope.From, envelope.KeyVersion)
+	if err != nil {
+		s.logger.Warn("Unknown application key", zap.String("from", envelope.From), zap.String("key_version", envelope.KeyVersion), zap.String("error", err.Error()))
+		return c.JSON(http.StatusUnauthorized, responseBody{Error: true, Message: "unknown application key: " + envelope.From + "/" + envelope.KeyVersion})
+	}
+
+	if err := s.security.VerifyDigitalSignature(envelope.Message, envelope.Signature, applicationEd25519PublicKey); err != nil {
+		s.logger.Warn("Rejected request signature", zap.String("from", envelope.From), zap.String("error", err.Error()))
+		return c.JSON(http.StatusUnauthorized, responseBody{Error: true, Message: err.Error()})
+	}
+
+	method := http.MethodPost
+	requestID := ""
+	if envelope.Metadata != nil {
+		signingBytes, err := envelope.Metadata.SigningBytes(envelope.Message)
+		if err != nil {
+			s.logger.Warn("Rejected metadata", zap.String("from", envelope.From), zap.String("error", err.Error()))
+			return c.JSON(http.StatusBadRequest, responseBody{Error: true, Message: err.Error()})
+		}
+		if err := s.security.Verify(signingBytes, envelope.MetadataSignature, applicationEd25519PublicKey); err != nil {
+			s.logger.Warn("Rejected metadata signature", zap.String("from", envelope.From), zap.String("error", err.Error()))
+			return c.JSON(http.StatusUnauthorized, responseBody{Error: true, Message: "metadata " + err.Error()})
+		}
+		method = envelope.Metadata.Method
+		requestID = envelope.Metadata.RequestID
+	}
+
+	ciphertext, encryptedAESKey, nonce, err := s.security.DecodeBase64Encrypted(envelope.Message)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responseBody{Error: true, Message: err.Error()})
+	}
+	payload, err := s.security.Decrypt(s.keys.RSAPrivateKey, ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responseBody{Error: true, Message: "failed to decrypt: " + err.Error()})
+	}
+
+	s.mu.Lock()
+	s.received = append(s.received, ReceivedRequest{
+		Route:      route,
+		Method:     method,
+		RequestID:  requestID,
+		From:       envelope.From,
+		To:         envelope.To,
+		KeyVersion: envelope.KeyVersion,
+		Payload:    json.RawMessage(payload),
+		Header:     c.Request().Header.Clone(),
+	})
+	s.mu.Unlock()
+
+	s.logger.Info("Mock rapid request", zap.String("request_id", requestID), zap.String("method", method), zap.String("route", route), zap.String("from", envelope.From), zap.String("to", envelope.To), zap.Bool("fault", faulty))
+
+	if faulty {
+		switch scenario.Fault {
+		case FaultError:
+			status := scenario.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			message := scenario.ErrorMessage
+			if message == "" {
+				message = http.StatusText(status)
+			}
+			return c.JSON(status, responseBody{Error: true, Code: scenario.ErrorCode, Message: message})
+		case FaultMalformed:
+			return c.String(http.StatusOK, "this is not a rapid response")
+		case FaultDrop:
+			return s.drop(c)
+		}
+	}
+
+	response, err := s.seal(envelope, scenario.Response, applicationRsaPublicKey)
+	if err != nil {
+		s.logger.Error("Failed to seal response", zap.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, responseBody{Error: true, Message: err.Error()})
+	}
+
+	if faulty && scenario.Fault == FaultBadSignature {
+		response.Signature = corruptSignature(response.Signature)
+	}
+
+	status := scenario.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	// a signed error, e.g. a scripted 422 with the bank's reason
+	return c.JSON(status, responseBody{Data: &response, Error: status >= http.StatusBadRequest})
+}
+
+// seal encrypts the scripted response for the application and signs it with
+// the bank key, addressed back to the sender of the request.
+func (s *Server) seal(request Envelope, plaintext json.RawMessage, applicationRsaPublicKey *rsa.PublicKey) (Envelope, error) {
+	if len(plaintext) == 0 {
+		plaintext = json.RawMessage(`{}`)
+	}
+
+	ciphertext, encryptedAESKey, nonce, err := s.security.Encrypt(plaintext, applicationRsaPublicKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	signature, err := s.security.CreateDigitalSignature(s.keys.Ed25519PrivateKey, ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	message, err := s.security.CreateBase64Encrypted(ciphertext, encryptedAESKey, nonce)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		From:       request.To,
+		To:         request.From,
+		Message:    message,
+		Signature:  signature,
+		KeyVersion: request.KeyVersion,
+	}, nil
+}
+
+// drop closes the client connection without writing a response.
+func (s *Server) drop(c echo.Context) error {
+	hijacker, ok := c.Response().Writer.(http.Hijacker)
+	if !ok {
+		return c.NoContent(http.StatusServiceUnavailable)
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// corruptSignature returns a well formed signature that does not verify.
+func corruptSignature(signature string) string {
+	return strings.Repeat("A", len(signature)-2) + "=="
+}
@@ -0,0 +1,118 @@
+package mockrapid
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Fault replaces the normal response of a route to simulate a misbehaving
+// Rapid Links deployment.
+type Fault string
+
+const (
+	// FaultNone answers normally
+	FaultNone Fault = ""
+	// FaultError answers with Scenario.Status and an unencrypted error body
+	FaultError Fault = "error"
+	// FaultBadSignature answers with a properly encrypted response whose
+	// signature does not verify
+	FaultBadSignature Fault = "bad_signature"
+	// FaultMalformed answers with a body that is not JSON
+	FaultMalformed Fault = "malformed"
+	// FaultDrop closes the connection without answering
+	FaultDrop Fault = "drop"
+)
+
+// Duration is a time.Duration written as a string such as "250ms" in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"250ms\": %w", err)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Scenario scripts how the simulator answers one route.
+type Scenario struct {
+	// HTTP status of the response, 200 when unset, or 500 for FaultError
+	Status int `json:"status,omitempty"`
+	// added before answering
+	Latency Duration `json:"latency,omitempty"`
+	// plaintext returned to the application, encrypted and signed
+	Response json.RawMessage `json:"response,omitempty"`
+	Fault    Fault           `json:"fault,omitempty"`
+	// message of the error body for FaultError
+	ErrorMessage string `json:"error_message,omitempty"`
+	// code of the error body for FaultError
+	ErrorCode string `json:"error_code,omitempty"`
+	// fraction of requests, between 0 and 1, answered with the fault; 0
+	// applies the fault to every request
+	FaultRate float64 `json:"fault_rate,omitempty"`
+}
+
+// Scenarios maps a route, the request path after the resource prefix such as
+// "balance" or "payment/initiate", to its scenario.
+type Scenarios map[string]Scenario
+
+// DefaultScenarios answers every resource route of the bridge with a
+// successful canned response.
+func DefaultScenarios() Scenarios {
+	return Scenarios{
+		"balance": {
+			Response: json.RawMessage(`{"account_number":"0010000000001","currency":"NPR","available_balance":"125000.50","ledger_balance":"130000.00"}`),
+		},
+		"statement": {
+			Response: json.RawMessage(`{"account_number":"0010000000001","currency":"NPR","transactions":[{"date":"2025-01-02","description":"Opening deposit","amount":"130000.00","type":"credit"},{"date":"2025-01-05","description":"ATM withdrawal","amount":"4999.50","type":"debit"}]}`),
+		},
+		"payment/initiate": {
+			Response: json.RawMessage(`{"payment_id":"PAY-0001","status":"pending_approval"}`),
+		},
+		"payment/approve": {
+			Response: json.RawMessage(`{"payment_id":"PAY-0001","status":"completed"}`),
+		},
+		"account/open": {
+			Response: json.RawMessage(`{"account_number":"0010000000002","status":"active"}`),
+		},
+	}
+}
+
+// LoadScenarios reads scenarios from a JSON file and merges them over the
+// defaults, so a file only needs the routes it changes.
+func LoadScenarios(path string) (Scenarios, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenarios: %w", err)
+	}
+
+	loaded := Scenarios{}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("invalid scenarios %s: %w", path, err)
+	}
+
+	scenarios := DefaultScenarios()
+	for route, scenario := range loaded {
+		switch scenario.Fault {
+		case FaultNone, FaultError, FaultBadSignature, FaultMalformed, FaultDrop:
+		default:
+			return nil, fmt.Errorf("route %s: unknown fault %q", route, scenario.Fault)
+		}
+		scenarios[route] = scenario
+	}
+
+	return scenarios, nil
+}
@@ -0,0 +1,76 @@
+// Package age encrypts with a passphrase in the age v1 file format,
+// https://age-encryption.org/v1, so that backups can also be opened with the
+// age command line tool (age -d).
+package age
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/age"
+)
+
+const (
+	// DefaultWorkFactor is the scrypt log2(N) used by the age tool.
+	DefaultWorkFactor = 18
+	// files asking for more than this are rejected rather than tying up
+	// gigabytes of memory
+	maxWorkFactor = 22
+)
+
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase or corrupted header")
+var ErrUnsupportedFormat = errors.New("not a passphrase-encrypted age file")
+
+// Encrypt encrypts plaintext with a key derived from the passphrase.
+func Encrypt(plaintext []byte, passphrase string, workFactor int) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	recipient.SetWorkFactor(workFactor)
+
+	var out bytes.Buffer
+	writer, err := age.Encrypt(&out, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// Decrypt decrypts a file produced by Encrypt or by age -p.
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	identity.SetMaxWorkFactor(maxWorkFactor)
+
+	reader, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrIncorrectPassphrase
+		}
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("age payload could not be decrypted: %w", err)
+	}
+
+	return plaintext, nil
+}
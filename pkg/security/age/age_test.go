@@ -0,0 +1,101 @@
+package age
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// low enough to keep the tests fast
+const testWorkFactor = 10
+
+func TestRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 64 * 1024, 64*1024 + 1, 200 * 1024} {
+		plaintext := bytes.Repeat([]byte{0xa5}, size)
+
+		encrypted, err := Encrypt(plaintext, "correct horse", testWorkFactor)
+		if err != nil {
+			t.Fatalf("size %d: Encrypt: %v", size, err)
+		}
+		if !bytes.HasPrefix(encrypted, []byte("age-encryption.org/v1\n-> scrypt ")) {
+			t.Fatalf("size %d: not an age scrypt file: %q", size, encrypted[:40])
+		}
+
+		decrypted, err := Decrypt(encrypted, "correct horse")
+		if err != nil {
+			t.Fatalf("size %d: Decrypt: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("size %d: round trip changed the plaintext", size)
+		}
+	}
+}
+
+func TestEncryptRejectsEmptyPassphrase(t *testing.T) {
+	if _, err := Encrypt([]byte("data"), "", testWorkFactor); err == nil {
+		t.Fatal("Encrypt accepted an empty passphrase")
+	}
+}
+
+func TestDecryptWrongPassphrase(t *testing.T) {
+	encrypted, err := Encrypt([]byte("data"), "correct horse", testWorkFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Decrypt(encrypted, "battery staple"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Fatalf("Decrypt with the wrong passphrase = %v, want ErrIncorrectPassphrase", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	encrypted, err := Encrypt(bytes.Repeat([]byte("data"), 100), "correct horse", testWorkFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerEnd := bytes.Index(encrypted, []byte("\n---")) + 1
+
+	tests := []struct {
+		name   string
+		offset int
+	}{
+		{"header", headerEnd - 5},
+		{"mac", headerEnd + 10},
+		{"payload", len(encrypted) - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := bytes.Clone(encrypted)
+			tampered[tt.offset] ^= 0x01
+
+			if _, err := Decrypt(tampered, "correct horse"); err == nil {
+				t.Fatal("Decrypt accepted a tampered file")
+			}
+		})
+	}
+}
+
+func TestDecryptTruncated(t *testing.T) {
+	encrypted, err := Encrypt(bytes.Repeat([]byte("data"), 100), "correct horse", testWorkFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Decrypt(encrypted[:len(encrypted)-20], "correct horse"); err == nil {
+		t.Fatal("Decrypt accepted a truncated file")
+	}
+}
+
+func TestDecryptNotAge(t *testing.T) {
+	if _, err := Decrypt([]byte("PK\x03\x04 not an age file"), "correct horse"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("Decrypt of a non-age file = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestDecryptRejectsExcessiveWorkFactor(t *testing.T) {
+	// a stanza asking for 2^30 would otherwise allocate a gigabyte
+	file := []byte("age-encryption.org/v1\n-> scrypt AAAAAAAAAAAAAAAAAAAAAA 30\nAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\n--- AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\n")
+	if _, err := Decrypt(file, "correct horse"); err == nil {
+		t.Fatal("Decrypt accepted a work factor above the limit")
+	}
+}
@@ -1,6 +1,12 @@
 package hybridcrypto
 
-import "strings"
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
 
 func SplitMessage(message string) []string {
 	return strings.Split(message, "-")
@@ -16,3 +22,15 @@ func CreateMessageToSign(ciphertext, aesKey, nonce []byte) []byte {
 
 	return messageToSign
 }
+
+// PublicKeyFingerprint returns the hex encoded SHA-256 of the PKIX encoding
+// of an RSA or Ed25519 public key.
+func PublicKeyFingerprint(publicKey any) (string, error) {
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	sum := sha256.Sum256(publicKeyBytes)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,208 @@
+package tlsconfig
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"rapid-bridge/domain/port"
+	"rapid-bridge/pkg/util"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseVersion turns "1.2" or "1.3" into a tls version. Older versions are
+// not accepted.
+func ParseVersion(version string) (uint16, error) {
+	parsed, ok := versions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q, use 1.2 or 1.3", version)
+	}
+	return parsed, nil
+}
+
+// LoadCertPool reads a PEM bundle of CA certificates.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// SPKIPin is the pin of a certificate's public key, as accepted in
+// port.UpstreamTLS.SPKIPins.
+func SPKIPin(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+const spkiPinPrefix = "sha256/"
+
+// NewClientConfig builds the TLS config for connections to a bank's Rapid
+// Links. Files are read once; build a new config to pick up changes.
+func NewClientConfig(upstreamTLS port.UpstreamTLS) (*tls.Config, error) {
+	minVersion := uint16(tls.VersionTLS12)
+	if upstreamTLS.MinVersion != "" {
+		parsed, err := ParseVersion(upstreamTLS.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		minVersion = parsed
+	}
+
+	config := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: upstreamTLS.ServerName,
+	}
+
+	if upstreamTLS.CAFile != "" {
+		rootCAs, err := LoadCertPool(upstreamTLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = rootCAs
+	}
+
+	if (upstreamTLS.CertFile == "") != (upstreamTLS.KeyFile == "") {
+		return nil, errors.New("cert_file and key_file must be given together")
+	}
+	if upstreamTLS.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(upstreamTLS.CertFile, upstreamTLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if len(upstreamTLS.SPKIPins) > 0 {
+		pins := make([]string, 0, len(upstreamTLS.SPKIPins))
+		for _, pin := range upstreamTLS.SPKIPins {
+			if !strings.HasPrefix(pin, spkiPinPrefix) {
+				pin = spkiPinPrefix + pin
+			}
+			if decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, spkiPinPrefix)); err != nil || len(decoded) != sha256.Size {
+				return nil, fmt.Errorf("invalid SPKI pin %q, expected sha256/<base64 of 32 bytes>", pin)
+			}
+			pins = append(pins, pin)
+		}
+		// runs after the usual chain verification, so a pin narrows what is
+		// trusted and never replaces it
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, chain := range state.VerifiedChains {
+				for _, certificate := range chain {
+					if slices.Contains(pins, SPKIPin(certificate)) {
+						return nil
+					}
+				}
+			}
+			return errors.New("no certificate in the chain matches the configured SPKI pins")
+		}
+	}
+
+	return config, nil
+}
+
+type ServerOptions struct {
+	CertFile string
+	KeyFile  string
+	// PEM bundle of the CAs client certificates must chain to; empty means
+	// client certificates are not requested
+	ClientCAFile string
+	MinVersion   uint16
+}
+
+// ServerReloader hands out the server TLS config, re-reading the certificate,
+// key and client CA bundle when one of the files changes, so that renewed
+// certificates apply without a restart. A failed reload is logged and the
+// previous files stay in use.
+type ServerReloader struct {
+	options ServerOptions
+	logger  port.Logger
+
+	config *util.Reloader[*tls.Config]
+}
+
+func NewServerReloader(options ServerOptions, logger port.Logger) (*ServerReloader, error) {
+	// a broken set of files is not retried until it changes again, which
+	// also covers the moment between writing the certificate and the key
+	config, err := util.NewReloader(func() (*tls.Config, error) {
+		return loadServerConfig(options)
+	}, options.files()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServerReloader{
+		options: options,
+		logger:  logger,
+		config:  config,
+	}, nil
+}
+
+// TLSConfig is the config to serve with. Every handshake goes through
+// GetConfigForClient and so sees the latest certificate and client CAs.
+func (s *ServerReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: s.options.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.current(), nil
+		},
+	}
+}
+
+func (s *ServerReloader) current() *tls.Config {
+	config, reloaded, err := s.config.Current()
+	if err != nil {
+		s.logger.Error("Failed to reload TLS certificate, keeping the previous one", zap.String("error", err.Error()))
+	} else if reloaded {
+		s.logger.Info("TLS certificate reloaded", zap.String("cert", s.options.CertFile))
+	}
+	return config
+}
+
+func (o ServerOptions) files() []string {
+	files := []string{o.CertFile, o.KeyFile}
+	if o.ClientCAFile != "" {
+		files = append(files, o.ClientCAFile)
+	}
+	return files
+}
+
+func loadServerConfig(options ServerOptions) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   options.MinVersion,
+	}
+
+	if options.ClientCAFile != "" {
+		clientCAs, err := LoadCertPool(options.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
@@ -4,21 +4,41 @@ import (
 	"context"
 
 	"rapid-bridge/constants"
+	"rapid-bridge/domain/port"
 
 	"github.com/labstack/echo/v4"
 )
 
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the id of the request it belongs to.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// GetRequestID returns the request id of ctx, empty outside a request.
+func GetRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RequestLogger returns logger adding the request id of ctx to every line.
+func RequestLogger(ctx context.Context, logger port.Logger) port.Logger {
+	if requestID := GetRequestID(ctx); requestID != "" {
+		return logger.With(constants.RequestId, requestID)
+	}
+	return logger
+}
+
 func GetReqCtxFromEchoCtx(c echo.Context) context.Context {
 	ctx := c.Request().Context()
 
 	req := c.Request()
 
-	requestID := req.Header.Get("X-Request-ID")
 	from := req.Header.Get(constants.From)
 	to := req.Header.Get(constants.To)
 	keyVersion := req.Header.Get(constants.KeyVersion)
 
-	ctx = context.WithValue(ctx, constants.RequestId, requestID)
 	ctx = context.WithValue(ctx, constants.From, from)
 	ctx = context.WithValue(ctx, constants.To, to)
 	ctx = context.WithValue(ctx, constants.KeyVersion, keyVersion)
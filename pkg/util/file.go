@@ -1,11 +1,17 @@
 package util
 
 import (
+	"fmt"
+	"io"
+	"io/fs"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"rapid-bridge/constants"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/oklog/ulid/v2"
 )
@@ -18,33 +24,80 @@ func FileExists(path string) bool {
 	return !info.IsDir()
 }
 
-func GetRSAPrivateKeyPath(applicationSlug, newUlid string) string {
-	rsaPrivateKeyPath := filepath.Join(constants.RapidBridgeData, constants.Application, applicationSlug, newUlid, constants.RSAPrivateKeyFile)
+func GetRSAPrivateKeyPath(dataDir, applicationSlug, newUlid string) string {
+	rsaPrivateKeyPath := filepath.Join(dataDir, constants.Application, applicationSlug, newUlid, constants.RSAPrivateKeyFile)
 	return rsaPrivateKeyPath
 }
 
-func GetRSAPublicKeyPath(applicationSlug, newUlid string) string {
-	rsaPublicKeyPath := filepath.Join(constants.RapidBridgeData, constants.Application, applicationSlug, newUlid, constants.RSAPublicKeyFile)
+func GetRSAPublicKeyPath(dataDir, applicationSlug, newUlid string) string {
+	rsaPublicKeyPath := filepath.Join(dataDir, constants.Application, applicationSlug, newUlid, constants.RSAPublicKeyFile)
 	return rsaPublicKeyPath
 }
 
-func GetEd25519PrivateKeyPath(applicationSlug, newUlid string) string {
-	ed25519PrivateKeyPath := filepath.Join(constants.RapidBridgeData, constants.Application, applicationSlug, newUlid, constants.Ed25519PrivateKeyFile)
+func GetEd25519PrivateKeyPath(dataDir, applicationSlug, newUlid string) string {
+	ed25519PrivateKeyPath := filepath.Join(dataDir, constants.Application, applicationSlug, newUlid, constants.Ed25519PrivateKeyFile)
 	return ed25519PrivateKeyPath
 }
 
-func GetEd25519PublicKeyPath(applicationSlug, newUlid string) string {
-	ed25519PublicKeyPath := filepath.Join(constants.RapidBridgeData, constants.Application, applicationSlug, newUlid, constants.Ed25519PublicKeyFile)
+func GetEd25519PublicKeyPath(dataDir, applicationSlug, newUlid string) string {
+	ed25519PublicKeyPath := filepath.Join(dataDir, constants.Application, applicationSlug, newUlid, constants.Ed25519PublicKeyFile)
 	return ed25519PublicKeyPath
 }
 
-func GetBankRSAPublicKeyPath(bankSlug string) string {
-	return filepath.Join(constants.RapidBridgeData, constants.Bank, bankSlug, constants.RSAPublicKeyFile)
+func GetBankRSAPublicKeyPath(dataDir, bankSlug string) string {
+	return filepath.Join(dataDir, constants.Bank, bankSlug, constants.RSAPublicKeyFile)
 
 }
 
-func GetBankEd25519PublicKeyPath(bankSlug string) string {
-	return filepath.Join(constants.RapidBridgeData, constants.Bank, bankSlug, constants.Ed25519PublicKeyFile)
+func GetBankEd25519PublicKeyPath(dataDir, bankSlug string) string {
+	return filepath.Join(dataDir, constants.Bank, bankSlug, constants.Ed25519PublicKeyFile)
+}
+
+// IsPathElement reports whether name can be joined to a path under the data
+// directory as a single element, so that a slug taken from a request cannot
+// point outside of its own directory.
+func IsPathElement(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..") && !strings.ContainsFunc(name, unicode.IsControl)
+}
+
+// IsKeyVersion reports whether keyVersion is a key version, that is a ULID
+// in its canonical form.
+func IsKeyVersion(keyVersion string) bool {
+	_, err := ulid.ParseStrict(keyVersion)
+	return err == nil && strings.ToUpper(keyVersion) == keyVersion
+}
+
+// KeyVersionTime returns when the keys of keyVersion were generated, which
+// is the timestamp of its ULID.
+func KeyVersionTime(keyVersion string) (time.Time, bool) {
+	id, err := ulid.ParseStrict(keyVersion)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ulid.Time(id.Time()), true
+}
+
+// ApplicationKeyVersions lists the key version directories of an application
+// on disk, oldest first.
+func ApplicationKeyVersions(dataDir, applicationSlug string) []string {
+	keyVersions := []string{}
+	if !IsPathElement(applicationSlug) {
+		return keyVersions
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dataDir, constants.Application, applicationSlug))
+	if err != nil {
+		return keyVersions
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && IsKeyVersion(entry.Name()) {
+			keyVersions = append(keyVersions, entry.Name())
+		}
+	}
+	slices.Sort(keyVersions)
+
+	return keyVersions
 }
 
 func GenerateULID() ulid.ULID {
@@ -72,3 +125,104 @@ func CompareULIDs(ulids ...ulid.ULID) ulid.ULID {
 func ReadFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
+
+// CopyDir recursively copies the regular files and directories under src to
+// dst, keeping their permissions. dst must not exist yet.
+func CopyDir(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("destination already exists: %s", dst)
+	}
+
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relativePath)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// RemoveDirWithin removes dir and everything below it, refusing to do so when
+// dir is a symlink or does not resolve to a directory strictly inside root.
+func RemoveDirWithin(root, dir string) error {
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("refusing to remove symlink: %s", dir)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", dir)
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	resolvedRoot, err = filepath.Abs(resolvedRoot)
+	if err != nil {
+		return err
+	}
+
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	resolvedDir, err = filepath.Abs(resolvedDir)
+	if err != nil {
+		return err
+	}
+
+	relativePath, err := filepath.Rel(resolvedRoot, resolvedDir)
+	if err != nil {
+		return err
+	}
+	if relativePath == "." || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to remove %s outside of %s", dir, root)
+	}
+
+	return os.RemoveAll(resolvedDir)
+}
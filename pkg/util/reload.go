@@ -0,0 +1,124 @@
+package util
+
+import (
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// modTimeGranularity is the coarsest modification time resolution of the
+// file systems the bridge runs on. Two writes within one tick can leave the
+// modification time unchanged.
+const modTimeGranularity = 2 * time.Second
+
+type fileStat struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// FileStamp records the modification times and sizes of a set of files, to
+// tell whether one of them changed since they were read. A missing file is
+// recorded as such, so creating or removing it counts as a change.
+type FileStamp struct {
+	files   []fileStat
+	settled bool
+}
+
+// StatFiles stamps the files. Empty paths stand for files that are not
+// configured and always compare equal.
+func StatFiles(paths ...string) FileStamp {
+	now := time.Now()
+	stamp := FileStamp{files: make([]fileStat, len(paths)), settled: true}
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stamp.files[i] = fileStat{exists: true, modTime: info.ModTime(), size: info.Size()}
+		if now.Sub(info.ModTime()) < modTimeGranularity {
+			stamp.settled = false
+		}
+	}
+	return stamp
+}
+
+// Unchanged reports whether the files stamped in s are the ones stamped in
+// previous. A stamp taken within one modification time tick of a write is
+// never unchanged, since a second write in the same tick would go unseen.
+func (s FileStamp) Unchanged(previous FileStamp) bool {
+	return previous.settled && slices.Equal(s.files, previous.files)
+}
+
+// Reloader keeps a value loaded from a set of files and loads it again when
+// one of them changes. A failed reload keeps the last good value and is not
+// retried until the files change again.
+type Reloader[T any] struct {
+	paths func(T) []string
+	load  func() (T, error)
+
+	mu    sync.RWMutex
+	value T
+	stamp FileStamp
+}
+
+// NewReloader loads the value a first time. Unlike a reload, a failure is an
+// error here.
+func NewReloader[T any](load func() (T, error), paths ...string) (*Reloader[T], error) {
+	return NewReloaderFunc(load, func(T) []string { return paths })
+}
+
+// NewReloaderFunc is NewReloader for a set of files that depends on the
+// value, such as files named in another one. paths is called with the value
+// in use before every check.
+func NewReloaderFunc[T any](load func() (T, error), paths func(T) []string) (*Reloader[T], error) {
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	// stamped after loading: a write in between is within a tick of the
+	// stamp and so is read again
+	stamp := StatFiles(paths(value)...)
+	return &Reloader[T]{paths: paths, load: load, value: value, stamp: stamp}, nil
+}
+
+// Current returns the value, reloading it first when one of the files
+// changed. reloaded tells whether that happened; err is the error of a
+// failed reload, in which case the previous value is returned. Both are only
+// reported once per change, not for the quiet re-reads while a write is
+// still within its modification time tick.
+func (r *Reloader[T]) Current() (value T, reloaded bool, err error) {
+	r.mu.RLock()
+	value = r.value
+	previous := r.stamp
+	r.mu.RUnlock()
+
+	stamp := StatFiles(r.paths(value)...)
+	unchanged := stamp.Unchanged(previous)
+
+	if unchanged {
+		return value, false, nil
+	}
+
+	loaded, err := r.load()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := !slices.Equal(stamp.files, r.stamp.files)
+	if err != nil {
+		r.stamp = stamp
+		if !changed {
+			err = nil
+		}
+		return r.value, false, err
+	}
+	r.value = loaded
+	// the new value may name other files
+	r.stamp = StatFiles(r.paths(loaded)...)
+	return loaded, changed, nil
+}
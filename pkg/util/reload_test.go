@@ -0,0 +1,91 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value")
+	past := time.Now().Add(-time.Hour)
+
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errInvalid := errors.New("invalid")
+	load := func() (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if string(data) == "bad" {
+			return "", errInvalid
+		}
+		return string(data), nil
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		modTime      time.Time
+		wantValue    string
+		wantReloaded bool
+		wantErr      error
+	}{
+		{"unchanged", "one", past, "one", false, nil},
+		{"rewritten", "two", past.Add(time.Second), "two", true, nil},
+		{"invalid keeps last good", "bad", past.Add(2 * time.Second), "two", false, errInvalid},
+		{"invalid not retried", "bad", past.Add(2 * time.Second), "two", false, nil},
+		{"fixed", "six", past.Add(3 * time.Second), "six", true, nil},
+		{"written now", "ten", time.Now(), "ten", true, nil},
+		// same size and modification time as the write before, within its tick
+		{"written again in the same tick", "owt", time.Time{}, "owt", false, nil},
+	}
+
+	write("one", past)
+	reloader, err := NewReloader(load, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lastModTime time.Time
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modTime := tt.modTime
+			if modTime.IsZero() {
+				modTime = lastModTime
+			}
+			write(tt.content, modTime)
+			lastModTime = modTime
+
+			value, reloaded, err := reloader.Current()
+			if value != tt.wantValue || reloaded != tt.wantReloaded || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Current = %q, %v, %v, want %q, %v, %v", value, reloaded, err, tt.wantValue, tt.wantReloaded, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileStampMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value")
+	missing := StatFiles(path, "")
+
+	if !StatFiles(path, "").Unchanged(missing) {
+		t.Fatal("missing file reported as changed")
+	}
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if StatFiles(path, "").Unchanged(missing) {
+		t.Fatal("created file reported as unchanged")
+	}
+}